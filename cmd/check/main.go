@@ -1,12 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"strings"
 
-	"github.com/hashicorp/go-version"
+	gogitea "code.gitea.io/sdk/gitea"
 	"github.com/mitchellh/colorstring"
+
 	"github.com/yorinasub17/concourse-gitea-release-resource/cmd"
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/gitea"
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/resource"
@@ -16,56 +17,94 @@ func main() {
 	request := resource.CheckRequest{}
 	cmd.InputRequest(&request)
 
-	semverConstraint := request.Source.SemverConstraint
-	emptyVersion := resource.Version{}
-	if request.Version != emptyVersion {
-		// If request has a version, constrain to only include those after the current version.
-		v, err := version.NewVersion(request.Version.Tag)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing version from existing release tag: %s\n"), err)
-			os.Exit(1)
-		}
-		greaterThan := "> " + v.String()
-		if semverConstraint == "" {
-			semverConstraint = greaterThan
-		} else {
-			semverConstraint = strings.Join([]string{semverConstraint, greaterThan}, ", ")
-		}
-	}
-
-	clt, err := gitea.NewGiteaClient(request.Source.GiteaURL, request.Source.AccessToken)
+	checkMode, err := gitea.NewCheckMode(request.Source.CheckMode)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing gitea client: %s\n"), err)
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing check mode: %s\n"), err)
 		os.Exit(1)
 	}
 
-	opts, err := gitea.NewListReleaseOpts(
-		request.Source.Owner,
-		request.Source.Repository,
-		semverConstraint,
-		request.Source.PreRelease,
-	)
+	missingVersion, err := gitea.NewMissingVersionPolicy(request.Source.OnMissingVersion)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing list filters: %s\n"), err)
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing missing version policy: %s\n"), err)
 		os.Exit(1)
 	}
 
-	filteredReleases, err := gitea.GetReleases(clt, *opts)
+	_, clt := cmd.NewClients(request.Source)
+
+	opts := cmd.NewListReleaseOpts(request.Source)
+
+	filteredReleases, err := gitea.GetReleases(clt, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error getting releases: %s\n"), err)
 		os.Exit(1)
 	}
 
-	outputVersions := []resource.Version{}
-	if len(filteredReleases) > 0 && request.Version == emptyVersion {
-		// If there are releases and request didn't include a version, return the first release.
-		outputVersions = append(outputVersions, resource.VersionFromRelease(filteredReleases[0]))
-	} else if len(filteredReleases) > 0 {
-		// If there are releases, and request included a version, return all releases.
-		for _, release := range filteredReleases {
-			outputVersions = append(outputVersions, resource.VersionFromRelease(release))
+	// The current version is irrelevant when only the newest release is returned, so it is only looked up for the other
+	// check modes.
+	var current *gogitea.Release
+	missing := false
+	emptyVersion := resource.Version{}
+	if request.Version != emptyVersion && checkMode != gitea.CheckLatest {
+		current, err = gitea.GetVersionRelease(
+			clt, request.Source.Owner, request.Source.Repository, request.Version.ID, request.Version.Tag,
+		)
+		switch {
+		case errors.Is(err, gitea.ErrMissingVersion) && missingVersion == gitea.MissingVersionFail:
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error checking current version: %s\n"), err)
+			os.Exit(1)
+		case errors.Is(err, gitea.ErrMissingVersion):
+			fmt.Fprintf(
+				os.Stderr,
+				colorstring.Color("[yellow]%s: applying on_missing_version policy %s\n"),
+				err, missingVersion,
+			)
+			missing = true
+		case err != nil:
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error getting current release: %s\n"), err)
+			os.Exit(1)
 		}
 	}
-	// For all other cases, return empty release list.
+
+	switch {
+	case missing && missingVersion == gitea.MissingVersionEmpty:
+		filteredReleases = nil
+	case checkMode == gitea.CheckLatest, missing:
+		// Only the newest release is returned, regardless of the current version, or when the current version is missing.
+		if len(filteredReleases) > 1 {
+			filteredReleases = filteredReleases[:1]
+		}
+	case checkMode == gitea.CheckEvery:
+		// All releases are returned in the same order that is used for determining the newer releases, along with the
+		// current version if it is still available, as Concourse expects.
+		gitea.SortReleases(filteredReleases, opts)
+		if current != nil {
+			newer := gitea.ReleasesSortedBefore(filteredReleases, current, opts)
+			if containsRelease(filteredReleases, current) {
+				newer = append(newer, current)
+			}
+			filteredReleases = newer
+		}
+	case current != nil:
+		// If request has a version, constrain to only include those after the current version.
+		filteredReleases = gitea.NewerReleases(filteredReleases, current, opts)
+	case len(filteredReleases) > 1:
+		// If request didn't include a version, return the latest release.
+		filteredReleases = filteredReleases[:1]
+	}
+
+	// Releases are returned newest first, but Concourse expects the versions in order from oldest to newest.
+	outputVersions := []resource.Version{}
+	for i := len(filteredReleases) - 1; i >= 0; i-- {
+		outputVersions = append(outputVersions, resource.VersionFromRelease(filteredReleases[i]))
+	}
 	cmd.OutputResponse(outputVersions)
 }
+
+func containsRelease(releases []*gogitea.Release, release *gogitea.Release) bool {
+	for _, r := range releases {
+		if r.ID == release.ID {
+			return true
+		}
+	}
+	return false
+}
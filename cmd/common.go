@@ -3,9 +3,16 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	gohttp "net/http"
 	"os"
+	"regexp"
 
+	gogitea "code.gitea.io/sdk/gitea"
 	"github.com/mitchellh/colorstring"
+
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/gitea"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/http"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/resource"
 )
 
 func InputRequest(request interface{}) {
@@ -21,3 +28,86 @@ func OutputResponse(response interface{}) {
 		os.Exit(1)
 	}
 }
+
+// NewClients returns the HTTP client configured with the TLS settings and credentials of the given source, along with a
+// Gitea API client that uses it. The HTTP client should be used for all requests to the Gitea server that don't go
+// through the API client, such as asset downloads. Transient failures are retried DefaultMaxRetries times unless the
+// source configures max_retries.
+func NewClients(src resource.Source) (*gohttp.Client, *gogitea.Client) {
+	maxRetries := http.DefaultMaxRetries
+	if src.MaxRetries != nil {
+		maxRetries = *src.MaxRetries
+	}
+
+	httpClt, err := http.NewClient(http.ClientOpts{
+		ServerURL:          src.GiteaURL,
+		AccessToken:        src.AccessToken,
+		CACert:             src.CACert,
+		InsecureSkipVerify: src.InsecureSkipVerify,
+		MaxRetries:         maxRetries,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing http client: %s\n"), err)
+		os.Exit(1)
+	}
+
+	clt, err := gitea.NewGiteaClient(src.GiteaURL, src.AccessToken, httpClt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing gitea client: %s\n"), err)
+		os.Exit(1)
+	}
+	return httpClt, clt
+}
+
+// NewListReleaseOpts returns the options for listing the releases that match the filters configured in the given
+// source.
+func NewListReleaseOpts(src resource.Source) gitea.ListReleaseOpts {
+	opts, err := gitea.NewListReleaseOpts(src.Owner, src.Repository, src.SemverConstraint, src.PreRelease)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing list filters: %s\n"), err)
+		os.Exit(1)
+	}
+	opts.OrderBy, err = gitea.NewReleaseOrder(src.OrderBy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing list filters: %s\n"), err)
+		os.Exit(1)
+	}
+	opts.Drafts, err = gitea.NewDraftFilter(src.Drafts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing list filters: %s\n"), err)
+		os.Exit(1)
+	}
+	if src.TagFilter != "" {
+		opts.TagFilter, err = regexp.Compile(src.TagFilter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing tag filter: %s\n"), err)
+			os.Exit(1)
+		}
+	}
+	opts.TargetCommitish, err = gitea.NewTargetCommitishFilter(src.TargetCommitish)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing list filters: %s\n"), err)
+		os.Exit(1)
+	}
+	if src.TitleRegex != "" {
+		opts.TitleFilter, err = regexp.Compile(src.TitleRegex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing title regex: %s\n"), err)
+			os.Exit(1)
+		}
+	}
+	if src.BodyRegex != "" {
+		opts.BodyFilter, err = regexp.Compile(src.BodyRegex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing body regex: %s\n"), err)
+			os.Exit(1)
+		}
+	}
+	opts.RequiredAssets, err = gitea.NewRequiredAssetsFilter(src.RequireAssets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing list filters: %s\n"), err)
+		os.Exit(1)
+	}
+	opts.ReleaseAuthors = src.ReleaseAuthor
+	return *opts
+}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	gohttp "net/http"
 	"os"
 	"path/filepath"
 
+	gogitea "code.gitea.io/sdk/gitea"
 	"github.com/mitchellh/colorstring"
 	"github.com/yorinasub17/concourse-gitea-release-resource/cmd"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/archive"
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/gitea"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/http"
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/resource"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/signature"
 )
 
 func main() {
@@ -22,12 +28,18 @@ func main() {
 
 	destDir := os.Args[1]
 
-	clt, err := gitea.NewGiteaClient(request.Source.GiteaURL, request.Source.AccessToken)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing gitea client: %s\n"), err)
-		os.Exit(1)
+	var verifier *signature.Verifier
+	if sigConfig := request.Source.VerifySignature; sigConfig != nil {
+		var err error
+		verifier, err = signature.NewVerifier(sigConfig.GPGPublicKeys, sigConfig.MinisignPublicKeys)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing trusted signing keys: %s\n"), err)
+			os.Exit(1)
+		}
 	}
 
+	httpClt, clt := cmd.NewClients(request.Source)
+
 	// Try fetching by ID first, and then by tag
 	maybeRel, err := gitea.GetReleaseByID(clt, request.Source.Owner, request.Source.Repository, request.Version.ID)
 	if err != nil {
@@ -77,7 +89,22 @@ func main() {
 	}
 	writeOutput(destDir, "timestamp", string(ts))
 
-	if len(maybeRel.Attachments) > 0 {
+	// The full release and the list of all its assets, including the ones that are not downloaded due to the globs.
+	writeJSONOutput(destDir, "release.json", maybeRel)
+	attachments := maybeRel.Attachments
+	if attachments == nil {
+		attachments = []*gogitea.Attachment{}
+	}
+	writeJSONOutput(destDir, "assets.json", attachments)
+
+	if request.Params.IncludeSourceZip {
+		downloadSourceArchive(httpClt, maybeRel.ZipURL, filepath.Join(destDir, "source.zip"))
+	}
+	if request.Params.IncludeSourceTarball {
+		downloadSourceArchive(httpClt, maybeRel.TarURL, filepath.Join(destDir, "source.tar.gz"))
+	}
+
+	if len(maybeRel.Attachments) > 0 && !request.Params.SkipDownload {
 		assetsDir := filepath.Join(destDir, "assets")
 		if err := os.Mkdir(assetsDir, 0755); err != nil {
 			fmt.Fprintf(
@@ -88,7 +115,9 @@ func main() {
 			os.Exit(1)
 		}
 
-		if err := gitea.DownloadReleaseAssets(clt, maybeRel, assetsDir, request.Params.Globs); err != nil {
+		if err := gitea.DownloadReleaseAssets(
+			clt, httpClt, maybeRel, assetsDir, request.Params.Globs, request.Params.Parallelism,
+		); err != nil {
 			fmt.Fprintf(
 				os.Stderr,
 				colorstring.Color("[red]error downloading release assets to dest dir %s: %s\n"),
@@ -96,6 +125,26 @@ func main() {
 			)
 			os.Exit(1)
 		}
+
+		if request.Params.VerifyChecksums {
+			if err := gitea.VerifyReleaseAssetChecksums(httpClt, maybeRel, assetsDir, request.Params.Globs); err != nil {
+				fmt.Fprintf(os.Stderr, colorstring.Color("[red]error verifying release asset checksums: %s\n"), err)
+				os.Exit(1)
+			}
+		}
+
+		if verifier != nil {
+			if err := gitea.VerifyReleaseAssetSignatures(
+				httpClt, maybeRel, assetsDir, request.Params.Globs, verifier,
+			); err != nil {
+				fmt.Fprintf(os.Stderr, colorstring.Color("[red]error verifying release asset signatures: %s\n"), err)
+				os.Exit(1)
+			}
+		}
+
+		if request.Params.Unpack {
+			unpackAssets(assetsDir)
+		}
 	}
 
 	resp := resource.InOutResponse{
@@ -105,6 +154,62 @@ func main() {
 	cmd.OutputResponse(resp)
 }
 
+// downloadSourceArchive downloads the source archive of the release tag that Gitea generates at the given URL.
+func downloadSourceArchive(httpClt *gohttp.Client, url, destPath string) {
+	if url == "" {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]release has no source archive URL for %s\n"), filepath.Base(destPath))
+		os.Exit(1)
+	}
+	if err := http.DownloadFileOverHTTP(httpClt, url, destPath, -1); err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error downloading release source archive %s: %s\n"), url, err)
+		os.Exit(1)
+	}
+}
+
+// unpackAssets extracts each downloaded archive asset into its own directory in the assets directory, named after the
+// archive without the extension (e.g., bundle.tar.gz is extracted into bundle). The archives are extracted after the
+// checksum and signature verification, and the directories must not exist yet, so that the verified assets are never
+// overwritten by the archive contents.
+func unpackAssets(assetsDir string) {
+	entries, err := os.ReadDir(assetsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error listing release assets in %s: %s\n"), assetsDir, err)
+		os.Exit(1)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !archive.IsArchive(entry.Name()) {
+			continue
+		}
+		destDir := filepath.Join(assetsDir, archive.TrimExtension(entry.Name()))
+		if _, err := os.Lstat(destDir); err == nil {
+			fmt.Fprintf(
+				os.Stderr,
+				colorstring.Color("[red]error unpacking release asset %s: %s already exists\n"),
+				entry.Name(), filepath.Base(destDir),
+			)
+			os.Exit(1)
+		}
+		if err := os.Mkdir(destDir, 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error unpacking release asset %s: %s\n"), entry.Name(), err)
+			os.Exit(1)
+		}
+		if err := archive.Extract(filepath.Join(assetsDir, entry.Name()), destDir); err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error unpacking release asset: %s\n"), err)
+			os.Exit(1)
+		}
+	}
+}
+
+func writeJSONOutput(destDir, fname string, v interface{}) {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error marshalling %s: %s\n"), fname, err)
+		os.Exit(1)
+	}
+	writeOutput(destDir, fname, string(content))
+}
+
 func writeOutput(destDir, fname, content string) {
 	path := filepath.Join(destDir, fname)
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
@@ -2,16 +2,22 @@ package main
 
 import (
 	"fmt"
+	gohttp "net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	gogitea "code.gitea.io/sdk/gitea"
 	"github.com/mattn/go-zglob"
 	"github.com/mitchellh/colorstring"
 
 	"github.com/yorinasub17/concourse-gitea-release-resource/cmd"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/checksum"
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/gitea"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/http"
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/resource"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/signature"
 )
 
 func main() {
@@ -25,8 +31,58 @@ func main() {
 
 	srcDir := os.Args[1]
 
+	if request.Params.Draft && request.Params.Publish {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]only one of draft or publish can be set\n"))
+		os.Exit(1)
+	}
+
+	if request.Params.GenerateNotes && request.Params.BodyPath != "" {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]only one of body_path or generate_notes can be set\n"))
+		os.Exit(1)
+	}
+
+	onConflict, err := gitea.NewAssetConflictStrategy(request.Params.AssetConflict)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing asset conflict strategy: %s\n"), err)
+		os.Exit(1)
+	}
+
+	var bumpLevel gitea.BumpLevel
+	if request.Params.Bump != "" {
+		if request.Params.TagPath != "" || request.Params.IDPath != "" {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]bump can not be combined with tag_path or id_path\n"))
+			os.Exit(1)
+		}
+		bumpLevel, err = gitea.NewBumpLevel(request.Params.Bump)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing bump level: %s\n"), err)
+			os.Exit(1)
+		}
+	}
+
+	var checksumAlgorithm checksum.Algorithm
+	if request.Params.GenerateChecksums != "" {
+		checksumAlgorithm, err = checksum.NewAlgorithm(request.Params.GenerateChecksums)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing checksum algorithm: %s\n"), err)
+			os.Exit(1)
+		}
+	}
+
+	var signer *signature.Signer
+	if request.Source.SigningKey != "" {
+		signer, err = signature.NewSigner(request.Source.SigningKey, request.Source.SigningKeyPassphrase)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error loading signing key: %s\n"), err)
+			os.Exit(1)
+		}
+	}
+
 	name := readFile(srcDir, request.Params.NamePath)
-	tag := readFile(srcDir, request.Params.TagPath)
+	var tag string
+	if bumpLevel == "" {
+		tag = readFile(srcDir, request.Params.TagPath)
+	}
 	target := readFile(srcDir, request.Params.TargetPath)
 
 	var body *string = nil
@@ -41,10 +97,10 @@ func main() {
 		idStr = &rawIDStr
 	}
 
-	clt, err := gitea.NewGiteaClient(request.Source.GiteaURL, request.Source.AccessToken)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error constructing gitea client: %s\n"), err)
-		os.Exit(1)
+	httpClt, clt := cmd.NewClients(request.Source)
+
+	if bumpLevel != "" {
+		tag = nextReleaseTag(clt, request.Source, bumpLevel)
 	}
 
 	// If id is provided, assume the release already exists and attempt to retrieve it so that it can be updated.
@@ -64,14 +120,77 @@ func main() {
 		}
 	}
 
+	filePaths := globFiles(srcDir, request.Params.Globs)
+
+	current := currentRelease(maybeExistingRel, tag, target)
+	var previousTag string
+	if request.Params.GenerateNotes || request.Params.Template {
+		previousTag = previousReleaseTag(clt, request.Source, current)
+	}
+
+	if request.Params.Template {
+		data := releaseTemplateData{
+			Tag:         tag,
+			Target:      current.Target,
+			PreviousTag: previousTag,
+			Assets:      assetNames(filePaths),
+			Build:       resource.BuildMetadataFromEnv(),
+		}
+		name = renderReleaseTemplate(request.Params.NamePath, name, data)
+		if body != nil {
+			renderedBody := renderReleaseTemplate(request.Params.BodyPath, *body, data)
+			body = &renderedBody
+		}
+	}
+
+	if request.Params.GenerateNotes {
+		notes := generateReleaseNotes(
+			clt, httpClt, srcDir, request.Source, request.Params, tag, current.Target, previousTag,
+		)
+		body = &notes
+	}
+
 	// If the release doesn't already exist, create it. Otherwise, update the existing release with the provided
 	// information. Note that in this scenario, only the name, body, and assets are updated to the provided values.
 	if maybeExistingRel == nil {
-		maybeExistingRel = createNewRelease(clt, srcDir, request.Source, name, tag, target, body)
+		maybeExistingRel = createNewRelease(clt, srcDir, request.Source, request.Params, name, tag, target, body)
 	} else {
 		maybeExistingRel = updateExistingRelease(clt, maybeExistingRel, srcDir, request.Source, name, tag, body)
 	}
-	uploadReleaseAssets(clt, maybeExistingRel, srcDir, request.Source, request.Params.Globs)
+
+	// The signatures are uploaded along with the files they sign, so that they are handled the same way on conflicts.
+	uploadPaths := filePaths
+	if signer != nil {
+		sigDir := makeTempDir("signatures-*")
+		defer os.RemoveAll(sigDir)
+		uploadPaths = append(append([]string{}, filePaths...), signFiles(signer, sigDir, filePaths)...)
+	}
+	// With the skip policy, the existing assets are kept instead of uploading the local files with the same name, so the
+	// checksum manifest must list the checksums of the kept assets.
+	var keptAssets []*gogitea.Attachment
+	if onConflict == gitea.SkipConflictingAssets {
+		keptAssets = maybeExistingRel.Attachments
+	}
+	assets := uploadReleaseAssets(clt, maybeExistingRel, uploadPaths, request.Source, request.Params, onConflict)
+
+	// The checksum manifest is generated from the assets of this put, and always replaces the manifest from a previous
+	// put.
+	if checksumAlgorithm != "" {
+		manifestAssets := uploadChecksumManifest(
+			clt, httpClt, maybeExistingRel, filePaths, keptAssets, request.Source, checksumAlgorithm, signer,
+		)
+		assets = append(assets, manifestAssets...)
+	}
+
+	// Prune after uploading so that the release never goes without the assets that are being replaced.
+	if request.Params.PruneAssets {
+		pruneReleaseAssets(clt, maybeExistingRel, request.Source, assets)
+	}
+
+	// Publish the release only after the assets are uploaded, so that the release is never public without its assets.
+	if request.Params.Publish && maybeExistingRel.IsDraft {
+		maybeExistingRel = publishRelease(clt, maybeExistingRel, request.Source)
+	}
 
 	resp := resource.InOutResponse{
 		Version:  resource.VersionFromRelease(maybeExistingRel),
@@ -84,16 +203,19 @@ func createNewRelease(
 	clt *gogitea.Client,
 	srcDir string,
 	src resource.Source,
+	params resource.OutParams,
 	name, tag, target string,
 	body *string,
 ) *gogitea.Release {
+	// When publishing, the release is staged as a draft until the assets are uploaded.
 	opts := gitea.CreateReleaseOpts{
 		Owner:        src.Owner,
 		Repo:         src.Repository,
 		Tag:          tag,
 		Target:       target,
 		Title:        name,
-		IsPreRelease: src.PreRelease,
+		IsPreRelease: src.PreRelease || params.Bump == string(gitea.BumpPrerelease),
+		IsDraft:      params.Draft || params.Publish,
 	}
 	if body != nil {
 		opts.Body = *body
@@ -126,6 +248,7 @@ func updateExistingRelease(
 		Body:         rel.Note,
 		Title:        name,
 		IsPreRelease: rel.IsPrerelease,
+		IsDraft:      rel.IsDraft,
 	}
 	if body != nil {
 		opts.Body = *body
@@ -142,13 +265,120 @@ func updateExistingRelease(
 	return rel
 }
 
-func uploadReleaseAssets(
+// nextReleaseTag returns the tag for the next version after the highest semver release, bumped at the given level.
+// Only the tag filter and semver constraint are applied when listing the releases, and prereleases and drafts are
+// included, so that the version is bumped from the highest of all the releases in the configured version line. Exits
+// with an error when the next tag already exists, rather than updating the release of that tag.
+func nextReleaseTag(clt *gogitea.Client, src resource.Source, level gitea.BumpLevel) string {
+	sourceOpts := cmd.NewListReleaseOpts(src)
+	opts := gitea.ListReleaseOpts{
+		Owner:             src.Owner,
+		Repo:              src.Repository,
+		SemverConstraint:  sourceOpts.SemverConstraint,
+		TagFilter:         sourceOpts.TagFilter,
+		IncludePreRelease: true,
+		Drafts:            gitea.IncludeDrafts,
+	}
+	releases, err := gitea.GetReleases(clt, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error getting releases: %s\n"), err)
+		os.Exit(1)
+	}
+
+	tag, err := gitea.NextReleaseTag(releases, opts.TagFilter, level)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error computing next release tag: %s\n"), err)
+		os.Exit(1)
+	}
+
+	exists, err := gitea.TagExists(clt, src.Owner, src.Repository, tag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error checking if tag %s exists: %s\n"), tag, err)
+		os.Exit(1)
+	}
+	if !exists {
+		// Draft releases don't have a tag until they are published, so they are looked up separately.
+		rel, err := gitea.GetReleaseByTag(clt, src.Owner, src.Repository, tag)
+		exists = err == nil && rel != nil
+	}
+	if exists {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]next release tag %s already exists\n"), tag)
+		os.Exit(1)
+	}
+	return tag
+}
+
+// currentRelease returns the existing release, or a stand-in for the release that is about to be created for comparing
+// it with the other releases.
+func currentRelease(existingRel *gogitea.Release, tag, target string) *gogitea.Release {
+	if existingRel != nil {
+		return existingRel
+	}
+	return &gogitea.Release{TagName: tag, Target: target, PublishedAt: time.Now()}
+}
+
+// previousReleaseTag returns the tag of the newest published release that is older than the current release, or an
+// empty string if there is none.
+func previousReleaseTag(clt *gogitea.Client, src resource.Source, current *gogitea.Release) string {
+	// Drafts are never considered as the previous release, since their tags may not exist yet.
+	opts := cmd.NewListReleaseOpts(src)
+	opts.Drafts = gitea.ExcludeDrafts
+	releases, err := gitea.GetReleases(clt, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error getting releases: %s\n"), err)
+		os.Exit(1)
+	}
+	if prev := gitea.PreviousRelease(releases, current, opts); prev != nil {
+		return prev.TagName
+	}
+	return ""
+}
+
+// generateReleaseNotes renders the release notes from the commits between the previous release and the target of the
+// release.
+func generateReleaseNotes(
 	clt *gogitea.Client,
-	release *gogitea.Release,
+	httpClt *gohttp.Client,
 	srcDir string,
 	src resource.Source,
-	globs []string,
-) {
+	params resource.OutParams,
+	tag, target, previousTag string,
+) string {
+	data, err := gitea.GetReleaseNotesData(
+		clt, httpClt, src.GiteaURL, src.Owner, src.Repository, tag, target, previousTag,
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error generating release notes: %s\n"), err)
+		os.Exit(1)
+	}
+
+	var tmpl string
+	if params.NotesTemplatePath != "" {
+		tmpl = readFile(srcDir, params.NotesTemplatePath)
+	}
+	notes, err := gitea.RenderReleaseNotes(tmpl, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error generating release notes: %s\n"), err)
+		os.Exit(1)
+	}
+	return notes
+}
+
+func publishRelease(clt *gogitea.Client, rel *gogitea.Release, src resource.Source) *gogitea.Release {
+	published, err := gitea.PublishRelease(clt, src.Owner, src.Repository, rel.ID)
+	if err != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			colorstring.Color("[red]error publishing release %d: %s\n"),
+			rel.ID, err,
+		)
+		os.Exit(1)
+	}
+	return published
+}
+
+func globFiles(srcDir string, globs []string) []string {
+	var filePaths []string
 	for _, fileGlob := range globs {
 		matches, err := zglob.Glob(filepath.Join(srcDir, fileGlob))
 		if err != nil {
@@ -159,21 +389,163 @@ func uploadReleaseAssets(
 			)
 			os.Exit(1)
 		}
+		filePaths = append(filePaths, matches...)
+	}
+	return filePaths
+}
 
-		if len(matches) == 0 {
+func uploadReleaseAssets(
+	clt *gogitea.Client,
+	release *gogitea.Release,
+	filePaths []string,
+	src resource.Source,
+	params resource.OutParams,
+	onConflict gitea.AssetConflictStrategy,
+) []*gogitea.Attachment {
+	assets, err := gitea.UploadReleaseAssets(
+		clt, src.Owner, src.Repository, release, filePaths, onConflict, params.Parallelism,
+	)
+	if err != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			colorstring.Color("[red]error uploading assets to release %d: %s\n"),
+			release.ID, err,
+		)
+		os.Exit(1)
+	}
+	return assets
+}
+
+// uploadChecksumManifest generates the checksum manifest for the given local files and uploads it to the release,
+// replacing any existing manifest. Local files that were not uploaded because an asset with the same name was kept are
+// checksummed from the kept asset instead, so that the manifest matches the assets on the release.
+func uploadChecksumManifest(
+	clt *gogitea.Client,
+	httpClt *gohttp.Client,
+	release *gogitea.Release,
+	filePaths []string,
+	keptAssets []*gogitea.Attachment,
+	src resource.Source,
+	algorithm checksum.Algorithm,
+	signer *signature.Signer,
+) []*gogitea.Attachment {
+	manifestName := algorithm.ManifestName()
+
+	tmpDir := makeTempDir("checksums-*")
+	defer os.RemoveAll(tmpDir)
+
+	keptByName := map[string]*gogitea.Attachment{}
+	for _, attachment := range keptAssets {
+		// When there are multiple assets with the same name, the last one wins, as when downloading the assets.
+		keptByName[attachment.Name] = attachment
+	}
+
+	// Leave out any local file with the same name as the manifest, since it is replaced by the generated manifest.
+	var checksumPaths []string
+	for _, path := range filePaths {
+		name := filepath.Base(path)
+		if name == manifestName {
 			continue
 		}
+		if kept, ok := keptByName[name]; ok {
+			path = downloadKeptAsset(httpClt, kept, filepath.Join(tmpDir, "kept"))
+		}
+		checksumPaths = append(checksumPaths, path)
+	}
+
+	manifest, err := checksum.GenerateManifest(algorithm, checksumPaths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error generating checksum manifest: %s\n"), err)
+		os.Exit(1)
+	}
+
+	manifestPath := filepath.Join(tmpDir, manifestName)
+	if err := os.WriteFile(manifestPath, manifest, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error writing checksum manifest %s: %s\n"), manifestPath, err)
+		os.Exit(1)
+	}
+	manifestPaths := []string{manifestPath}
+	if signer != nil {
+		manifestPaths = append(manifestPaths, signFiles(signer, tmpDir, manifestPaths)...)
+	}
 
-		for _, filePath := range matches {
-			if err := gitea.UploadReleaseAssetFromPath(clt, filePath, src.Owner, src.Repository, release.ID); err != nil {
-				fmt.Fprintf(
-					os.Stderr,
-					colorstring.Color("[red]error uploading asset %s: %s\n"),
-					filePath, err,
-				)
-				os.Exit(1)
-			}
+	// Refresh the release so that a manifest from a previous put is replaced, even if it was uploaded just now as one of
+	// the assets.
+	refreshed, err := gitea.GetReleaseByID(clt, src.Owner, src.Repository, strconv.FormatInt(release.ID, 10))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error getting release %d: %s\n"), release.ID, err)
+		os.Exit(1)
+	}
+
+	assets, err := gitea.UploadReleaseAssets(
+		clt, src.Owner, src.Repository, refreshed, manifestPaths, gitea.ReplaceConflictingAssets, 1,
+	)
+	if err != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			colorstring.Color("[red]error uploading checksum manifest to release %d: %s\n"),
+			release.ID, err,
+		)
+		os.Exit(1)
+	}
+	return assets
+}
+
+// downloadKeptAsset downloads the given asset that was kept on the release to destDir, returning the path of the
+// download.
+func downloadKeptAsset(httpClt *gohttp.Client, attachment *gogitea.Attachment, destDir string) string {
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error creating directory %s: %s\n"), destDir, err)
+		os.Exit(1)
+	}
+	destPath := filepath.Join(destDir, attachment.Name)
+	if err := http.DownloadFileOverHTTP(httpClt, attachment.DownloadURL, destPath, attachment.Size); err != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			colorstring.Color("[red]error downloading kept asset %s (%d) for checksum manifest: %s\n"),
+			attachment.Name, attachment.ID, err,
+		)
+		os.Exit(1)
+	}
+	return destPath
+}
+
+// signFiles writes the detached signatures of the files at the given paths to destDir, returning the paths of the
+// signatures.
+func signFiles(signer *signature.Signer, destDir string, filePaths []string) []string {
+	sigPaths := make([]string, 0, len(filePaths))
+	for _, path := range filePaths {
+		sigPath := filepath.Join(destDir, filepath.Base(path)+signature.SignedExtension)
+		if _, err := os.Stat(sigPath); err == nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error signing %s: multiple files with the same name\n"), path)
+			os.Exit(1)
+		}
+		if err := signer.SignFile(path, sigPath); err != nil {
+			fmt.Fprintf(os.Stderr, colorstring.Color("[red]error signing %s: %s\n"), path, err)
+			os.Exit(1)
 		}
+		sigPaths = append(sigPaths, sigPath)
+	}
+	return sigPaths
+}
+
+func makeTempDir(pattern string) string {
+	tmpDir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error creating temp dir: %s\n"), err)
+		os.Exit(1)
+	}
+	return tmpDir
+}
+
+func pruneReleaseAssets(clt *gogitea.Client, release *gogitea.Release, src resource.Source, keep []*gogitea.Attachment) {
+	if err := gitea.PruneReleaseAssets(clt, src.Owner, src.Repository, release.ID, keep); err != nil {
+		fmt.Fprintf(
+			os.Stderr,
+			colorstring.Color("[red]error pruning assets on release %d: %s\n"),
+			release.ID, err,
+		)
+		os.Exit(1)
 	}
 }
 
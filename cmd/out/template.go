@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mitchellh/colorstring"
+
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/resource"
+)
+
+// releaseTemplateData is the data that the name and body files are rendered with when the template param is set.
+type releaseTemplateData struct {
+	Tag         string
+	Target      string
+	PreviousTag string
+	// Assets are the names of the files that are uploaded to the release.
+	Assets []string
+	Build  resource.BuildMetadata
+}
+
+// renderReleaseTemplate renders the contents of the given source file as a text/template.
+func renderReleaseTemplate(fname, tmplStr string, data releaseTemplateData) string {
+	tmpl, err := template.New(fname).Option("missingkey=error").Parse(tmplStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error parsing template %s: %s\n"), fname, err)
+		os.Exit(1)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		fmt.Fprintf(os.Stderr, colorstring.Color("[red]error rendering template %s: %s\n"), fname, err)
+		os.Exit(1)
+	}
+	return out.String()
+}
+
+func assetNames(filePaths []string) []string {
+	names := make([]string, 0, len(filePaths))
+	for _, path := range filePaths {
+		names = append(names, filepath.Base(path))
+	}
+	return names
+}
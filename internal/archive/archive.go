@@ -0,0 +1,300 @@
+// Package archive contains routines for extracting the archive formats that are commonly used for release assets.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// format is an archive format that can be extracted, identified by the file extension.
+type format struct {
+	ext     string
+	extract func(archivePath, destDir string) error
+}
+
+var formats = []format{
+	{".tar.gz", extractTarGz},
+	{".tgz", extractTarGz},
+	{".tar", extractTar},
+	{".zip", extractZip},
+}
+
+// IsArchive returns whether the file with the given name is an archive that can be extracted, based on the extension.
+func IsArchive(name string) bool {
+	return findFormat(name) != nil
+}
+
+// Extract extracts the archive at the given path into destDir, overwriting any existing files. The archive format is
+// determined from the file extension (see IsArchive). Entries that would be extracted outside of destDir, either
+// directly or through a link, are rejected with an error.
+func Extract(archivePath, destDir string) error {
+	f := findFormat(filepath.Base(archivePath))
+	if f == nil {
+		return fmt.Errorf("unsupported archive format for %s", filepath.Base(archivePath))
+	}
+	if err := f.extract(archivePath, destDir); err != nil {
+		return fmt.Errorf("error extracting %s: %w", filepath.Base(archivePath), err)
+	}
+	return nil
+}
+
+// TrimExtension returns the given archive name without the archive extension (e.g., bundle for bundle.tar.gz). The name
+// is returned as is if it is not an archive.
+func TrimExtension(name string) string {
+	f := findFormat(name)
+	if f == nil {
+		return name
+	}
+	return name[:len(name)-len(f.ext)]
+}
+
+func findFormat(name string) *format {
+	lower := strings.ToLower(name)
+	for i := range formats {
+		if strings.HasSuffix(lower, formats[i].ext) {
+			return &formats[i]
+		}
+	}
+	return nil
+}
+
+func extractTarGz(archivePath, destDir string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	return extractTarStream(gz, destDir)
+}
+
+func extractTar(archivePath, destDir string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return extractTarStream(f, destDir)
+}
+
+func extractTarStream(r io.Reader, destDir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := makeDir(destDir, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(destDir, target, tr, hdr.FileInfo().Mode()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := writeSymlink(destDir, target, hdr.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if err := writeHardLink(destDir, target, hdr.Linkname); err != nil {
+				return err
+			}
+		default:
+			// Skip special files, like devices and FIFOs, which are not expected in release assets.
+		}
+	}
+}
+
+func extractZip(archivePath, destDir string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		target, err := safeJoin(destDir, zf.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			if err := makeDir(destDir, target); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			linkname, err := readZipFile(zf)
+			if err != nil {
+				return err
+			}
+			if err := writeSymlink(destDir, target, string(linkname)); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := zf.Open()
+			if err != nil {
+				return err
+			}
+			err = writeFile(destDir, target, rc, mode)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readZipFile(zf *zip.File) ([]byte, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// safeJoin joins the archive entry name to destDir, returning an error if the result is outside of destDir.
+func safeJoin(destDir, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("archive entry %s has an absolute path", name)
+	}
+	target := filepath.Join(destDir, name)
+	if !isWithin(destDir, target) {
+		return "", fmt.Errorf("archive entry %s is outside of the destination directory", name)
+	}
+	return target, nil
+}
+
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkResolved checks that path is still within destDir after resolving the symlinks in its longest existing prefix.
+// The lexical checks of safeJoin and writeSymlink alone can be bypassed with a chain of links, like a -> . and b -> a/..
+func checkResolved(destDir, path string) error {
+	realDestDir, err := filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return err
+	}
+
+	existing := path
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			rel, _ := filepath.Rel(existing, path)
+			if !isWithin(realDestDir, filepath.Join(resolved, rel)) {
+				return fmt.Errorf("%s resolves to a path outside of the destination directory", path)
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return err
+		}
+		existing = parent
+	}
+}
+
+// prepareTarget makes sure that the parent directory of target exists within destDir, and removes any existing file
+// at target, so that a link from an earlier entry is replaced instead of written through.
+func prepareTarget(destDir, target string) error {
+	if err := checkResolved(destDir, filepath.Dir(target)); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func makeDir(destDir, target string) error {
+	if err := checkResolved(destDir, target); err != nil {
+		return err
+	}
+	return os.MkdirAll(target, 0o755)
+}
+
+func writeFile(destDir, target string, r io.Reader, mode os.FileMode) (returnErr error) {
+	if err := prepareTarget(destDir, target); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); returnErr == nil {
+			returnErr = closeErr
+		}
+	}()
+
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// writeSymlink creates a symlink at target pointing to linkname, after checking that the link resolves to a path
+// within destDir.
+func writeSymlink(destDir, target, linkname string) error {
+	resolved := linkname
+	if !filepath.IsAbs(linkname) {
+		resolved = filepath.Join(filepath.Dir(target), linkname)
+	}
+	if !isWithin(destDir, resolved) {
+		return fmt.Errorf("archive entry %s links to %s, which is outside of the destination directory", target, linkname)
+	}
+
+	if err := prepareTarget(destDir, target); err != nil {
+		return err
+	}
+	return os.Symlink(linkname, target)
+}
+
+// writeHardLink creates a hard link at target to the earlier archive entry with the given name.
+func writeHardLink(destDir, target, linkname string) error {
+	linkTarget, err := safeJoin(destDir, linkname)
+	if err != nil {
+		return err
+	}
+	if err := checkResolved(destDir, linkTarget); err != nil {
+		return err
+	}
+
+	if err := prepareTarget(destDir, target); err != nil {
+		return err
+	}
+	return os.Link(linkTarget, target)
+}
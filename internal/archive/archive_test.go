@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEntry struct {
+	name     string
+	contents string
+	linkname string
+	dir      bool
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	valid := []testEntry{
+		{name: "bin/", dir: true},
+		{name: "bin/tool", contents: "binary"},
+		{name: "README.md", contents: "readme"},
+		{name: "docs/guide.md", contents: "guide"},
+		{name: "bin/link", linkname: "tool"},
+	}
+
+	testCases := []struct {
+		name        string
+		archiveName string
+		entries     []testEntry
+		expectErr   string
+	}{
+		{"TarGz", "bundle.tar.gz", valid, ""},
+		{"Tgz", "bundle.tgz", valid, ""},
+		{"Tar", "bundle.tar", valid, ""},
+		{"Zip", "bundle.zip", valid, ""},
+		{"TarTraversal", "bundle.tar.gz", []testEntry{{name: "../evil", contents: "x"}}, "outside of the destination"},
+		{"ZipTraversal", "bundle.zip", []testEntry{{name: "a/../../evil", contents: "x"}}, "outside of the destination"},
+		{"TarAbsolute", "bundle.tar", []testEntry{{name: "/tmp/evil", contents: "x"}}, "absolute path"},
+		{"TarSymlinkEscape", "bundle.tar", []testEntry{{name: "link", linkname: "../.."}}, "outside of the destination"},
+		{"ZipSymlinkEscape", "bundle.zip", []testEntry{{name: "link", linkname: "/etc"}}, "outside of the destination"},
+		{
+			"TarSymlinkChain",
+			"bundle.tar",
+			[]testEntry{
+				{name: "a", linkname: "."},
+				{name: "b", linkname: "a/.."},
+				{name: "b/evil", contents: "x"},
+			},
+			"outside of the destination",
+		},
+		{"Unsupported", "bundle.rar", valid, "unsupported archive format"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			destDir := filepath.Join(root, "assets")
+			require.NoError(t, os.Mkdir(destDir, 0o755))
+			archivePath := filepath.Join(destDir, tc.archiveName)
+			writeTestArchive(t, archivePath, tc.entries)
+
+			err := Extract(archivePath, destDir)
+			if tc.expectErr != "" {
+				assert.ErrorContains(t, err, tc.expectErr)
+				assert.NoFileExists(t, filepath.Join(root, "evil"))
+				return
+			}
+			require.NoError(t, err)
+
+			for _, e := range tc.entries {
+				if e.dir {
+					assert.DirExists(t, filepath.Join(destDir, e.name))
+					continue
+				}
+				contents, err := os.ReadFile(filepath.Join(destDir, e.name))
+				require.NoError(t, err)
+				if e.linkname != "" {
+					assert.Equal(t, "binary", string(contents))
+				} else {
+					assert.Equal(t, e.contents, string(contents))
+				}
+			}
+		})
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsArchive("bundle.tar.gz"))
+	assert.True(t, IsArchive("bundle.TGZ"))
+	assert.True(t, IsArchive("bundle.tar"))
+	assert.True(t, IsArchive("bundle.zip"))
+	assert.False(t, IsArchive("bundle.tar.gz.asc"))
+	assert.False(t, IsArchive("SHA256SUMS"))
+}
+
+func TestTrimExtension(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "bundle", TrimExtension("bundle.tar.gz"))
+	assert.Equal(t, "bundle", TrimExtension("bundle.TGZ"))
+	assert.Equal(t, "docs-1.2.3", TrimExtension("docs-1.2.3.zip"))
+	assert.Equal(t, "SHA256SUMS", TrimExtension("SHA256SUMS"))
+}
+
+func writeTestArchive(t *testing.T, path string, entries []testEntry) {
+	var buf bytes.Buffer
+	switch filepath.Ext(path) {
+	case ".zip":
+		zw := zip.NewWriter(&buf)
+		for _, e := range entries {
+			hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+			contents := e.contents
+			switch {
+			case e.dir:
+				hdr.SetMode(os.ModeDir | 0o755)
+			case e.linkname != "":
+				hdr.SetMode(os.ModeSymlink | 0o777)
+				contents = e.linkname
+			default:
+				hdr.SetMode(0o644)
+			}
+			w, err := zw.CreateHeader(hdr)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(contents))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+	case ".gz", ".tgz", ".tar":
+		var tw *tar.Writer
+		var gz *gzip.Writer
+		if filepath.Ext(path) == ".tar" {
+			tw = tar.NewWriter(&buf)
+		} else {
+			gz = gzip.NewWriter(&buf)
+			tw = tar.NewWriter(gz)
+		}
+		for _, e := range entries {
+			hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.contents)), Typeflag: tar.TypeReg}
+			switch {
+			case e.dir:
+				hdr = &tar.Header{Name: e.name, Mode: 0o755, Typeflag: tar.TypeDir}
+			case e.linkname != "":
+				hdr = &tar.Header{Name: e.name, Mode: 0o777, Linkname: e.linkname, Typeflag: tar.TypeSymlink}
+			}
+			require.NoError(t, tw.WriteHeader(hdr))
+			_, err := tw.Write([]byte(e.contents))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		if gz != nil {
+			require.NoError(t, gz.Close())
+		}
+	default:
+		buf.WriteString("not an archive")
+	}
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
@@ -0,0 +1,126 @@
+// Package checksum contains routines for generating and verifying checksum manifests of release assets. The manifests
+// use the same format as the sha256sum and sha512sum coreutils, so that they can also be verified with `sha256sum -c`.
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Algorithm is the hash algorithm to use for the checksums.
+type Algorithm string
+
+const (
+	SHA256 Algorithm = "sha256"
+	SHA512 Algorithm = "sha512"
+)
+
+// Algorithms lists all the supported algorithms, strongest first.
+var Algorithms = []Algorithm{SHA512, SHA256}
+
+// NewAlgorithm validates and returns the Algorithm for the given raw string.
+func NewAlgorithm(algorithmStr string) (Algorithm, error) {
+	switch algorithm := Algorithm(algorithmStr); algorithm {
+	case SHA256, SHA512:
+		return algorithm, nil
+	}
+	return "", fmt.Errorf("unknown checksum algorithm %q: must be one of %s or %s", algorithmStr, SHA256, SHA512)
+}
+
+// ManifestName returns the conventional file name of the checksum manifest for the algorithm (e.g., SHA256SUMS).
+func (a Algorithm) ManifestName() string {
+	return strings.ToUpper(string(a)) + "SUMS"
+}
+
+func (a Algorithm) newHash() hash.Hash {
+	if a == SHA512 {
+		return sha512.New()
+	}
+	return sha256.New()
+}
+
+// FileChecksum returns the hex encoded checksum of the file at the given path.
+func FileChecksum(algorithm Algorithm, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := algorithm.newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GenerateManifest returns a checksum manifest for the files at the given paths, listing each file by its basename. The
+// entries are sorted by name so that the manifest is the same regardless of the order of the paths.
+func GenerateManifest(algorithm Algorithm, paths []string) ([]byte, error) {
+	sums := map[string]string{}
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		sum, err := FileChecksum(algorithm, path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(path)
+		if _, exists := sums[name]; exists {
+			return nil, fmt.Errorf("multiple files named %s in checksum manifest", name)
+		}
+		sums[name] = sum
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifest bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&manifest, "%s  %s\n", sums[name], name)
+	}
+	return manifest.Bytes(), nil
+}
+
+// ParseManifest parses the given checksum manifest, returning the checksums keyed by file name. Both the text (`  `)
+// and binary (` *`) mode separators are supported.
+func ParseManifest(data []byte) (map[string]string, error) {
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		sum, name, found := strings.Cut(line, " ")
+		if !found || len(name) < 2 || (name[0] != ' ' && name[0] != '*') {
+			return nil, fmt.Errorf("invalid checksum manifest line %d: %q", lineNum, line)
+		}
+		if _, err := hex.DecodeString(sum); err != nil {
+			return nil, fmt.Errorf("invalid checksum on manifest line %d: %s", lineNum, err)
+		}
+		checksums[name[1:]] = strings.ToLower(sum)
+	}
+	return checksums, scanner.Err()
+}
+
+// VerifyFile checks that the file at the given path has the expected hex encoded checksum.
+func VerifyFile(algorithm Algorithm, path, expected string) error {
+	actual, err := FileChecksum(algorithm, path)
+	if err != nil {
+		return err
+	}
+	if actual != strings.ToLower(expected) {
+		return fmt.Errorf("%s checksum mismatch for %s: expected %s, got %s", algorithm, filepath.Base(path), expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAndVerifyManifest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fooPath := filepath.Join(dir, "foo.txt")
+	barPath := filepath.Join(dir, "bar.txt")
+	require.NoError(t, os.WriteFile(fooPath, []byte("foo"), 0o644))
+	require.NoError(t, os.WriteFile(barPath, []byte("bar"), 0o644))
+
+	testCases := []struct {
+		algorithm        Algorithm
+		expectedManifest string
+	}{
+		{
+			SHA256,
+			"fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9  bar.txt\n" +
+				"2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae  foo.txt\n",
+		},
+		{
+			SHA512,
+			"d82c4eb5261cb9c8aa9855edd67d1bd10482f41529858d925094d173fa662aa91ff39bc5b188615273484021dfb16fd8284cf684ccf0fc795be3aa2fc1e6c181  bar.txt\n" +
+				"f7fbba6e0636f890e56fbbf3283e524c6fa3204ae298382d624741d0dc6638326e282c41be5e4254d8820772c5518a2c5a8c0c7f7eda19594a7eb539453e1ed7  foo.txt\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(string(tc.algorithm), func(t *testing.T) {
+			t.Parallel()
+
+			manifest, err := GenerateManifest(tc.algorithm, []string{fooPath, barPath})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedManifest, string(manifest))
+
+			checksums, err := ParseManifest(manifest)
+			require.NoError(t, err)
+			assert.NoError(t, VerifyFile(tc.algorithm, fooPath, checksums["foo.txt"]))
+			assert.NoError(t, VerifyFile(tc.algorithm, barPath, checksums["bar.txt"]))
+			assert.Error(t, VerifyFile(tc.algorithm, fooPath, checksums["bar.txt"]))
+		})
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	t.Parallel()
+
+	checksums, err := ParseManifest([]byte("ABCDEF  foo.txt\n\nabcdef *bar baz.bin\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo.txt": "abcdef", "bar baz.bin": "abcdef"}, checksums)
+
+	_, err = ParseManifest([]byte("not a checksum line\n"))
+	assert.Error(t, err)
+
+	_, err = ParseManifest([]byte("abcdef\n"))
+	assert.Error(t, err)
+}
+
+func TestNewAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	algorithm, err := NewAlgorithm("sha512")
+	require.NoError(t, err)
+	assert.Equal(t, SHA512, algorithm)
+	assert.Equal(t, "SHA512SUMS", algorithm.ManifestName())
+
+	_, err = NewAlgorithm("md5")
+	assert.Error(t, err)
+}
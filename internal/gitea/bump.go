@@ -0,0 +1,140 @@
+package gitea
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/hashicorp/go-version"
+)
+
+// BumpLevel is the part of the semantic version that is incremented when computing the next release tag.
+type BumpLevel string
+
+const (
+	// BumpMajor increments the major version, resetting the minor and patch versions.
+	BumpMajor BumpLevel = "major"
+	// BumpMinor increments the minor version, resetting the patch version.
+	BumpMinor BumpLevel = "minor"
+	// BumpPatch increments the patch version.
+	BumpPatch BumpLevel = "patch"
+	// BumpPrerelease increments the prerelease number, starting a new prerelease of the next patch version if the
+	// current version is not a prerelease.
+	BumpPrerelease BumpLevel = "prerelease"
+)
+
+// defaultPrereleaseID is the prerelease identifier used when starting a new prerelease, as in 1.2.3-rc.1.
+const defaultPrereleaseID = "rc"
+
+// defaultTagPrefix is the prefix of the first release tag, when there are no releases to take the format from.
+const defaultTagPrefix = "v"
+
+// NewBumpLevel validates and returns the BumpLevel for the given raw string.
+func NewBumpLevel(levelStr string) (BumpLevel, error) {
+	switch level := BumpLevel(levelStr); level {
+	case BumpMajor, BumpMinor, BumpPatch, BumpPrerelease:
+		return level, nil
+	}
+	return "", fmt.Errorf(
+		"unknown bump level %q: must be one of %q, %q, %q, or %q",
+		levelStr, BumpMajor, BumpMinor, BumpPatch, BumpPrerelease,
+	)
+}
+
+// NextReleaseTag returns the tag for the next version after the highest semantic version among the given releases,
+// keeping the format of the tag of that release (e.g., the v prefix, or the parts of the tag outside of the tag filter
+// capture group). When there are no releases with a semver tag, the next version after 0.0.0 is used with a v prefix.
+func NextReleaseTag(releases []*gitea.Release, tagFilter *regexp.Regexp, level BumpLevel) (string, error) {
+	var highestTag string
+	var highest *version.Version
+	for _, release := range releases {
+		v, err := ReleaseVersion(release.TagName, tagFilter)
+		if err != nil {
+			continue
+		}
+		if highest == nil || v.GreaterThan(highest) {
+			highest, highestTag = v, release.TagName
+		}
+	}
+
+	var nextTag string
+	if highest == nil {
+		nextTag = defaultTagPrefix + BumpVersion(version.Must(version.NewVersion("0.0.0")), level)
+	} else {
+		nextTag = replaceTagVersion(highestTag, tagFilter, BumpVersion(highest, level))
+	}
+
+	if tagFilter != nil && !tagFilter.MatchString(nextTag) {
+		return "", fmt.Errorf("next release tag %s does not match tag filter %s", nextTag, tagFilter)
+	}
+	return nextTag, nil
+}
+
+// BumpVersion returns the next version after the given version at the given level, without any build metadata.
+// Bumping a prerelease to a major, minor, or patch level releases the version that the prerelease is for, when it is
+// at that level (e.g., 1.3.0-rc.1 bumped to minor is 1.3.0, but bumped to major is 2.0.0).
+func BumpVersion(v *version.Version, level BumpLevel) string {
+	segments := v.Segments()
+	major, minor, patch := segments[0], segments[1], segments[2]
+	prerelease := v.Prerelease()
+
+	switch level {
+	case BumpMajor:
+		if prerelease == "" || minor != 0 || patch != 0 {
+			major++
+		}
+		minor, patch, prerelease = 0, 0, ""
+	case BumpMinor:
+		if prerelease == "" || patch != 0 {
+			minor++
+		}
+		patch, prerelease = 0, ""
+	case BumpPatch:
+		if prerelease == "" {
+			patch++
+		}
+		prerelease = ""
+	case BumpPrerelease:
+		if prerelease == "" {
+			patch++
+			prerelease = defaultPrereleaseID + ".1"
+		} else {
+			prerelease = bumpPrerelease(prerelease)
+		}
+	}
+
+	out := fmt.Sprintf("%d.%d.%d", major, minor, patch)
+	if prerelease != "" {
+		out += "-" + prerelease
+	}
+	return out
+}
+
+// bumpPrerelease increments the last numeric identifier of the prerelease, or adds one if there is none (e.g., rc.1
+// becomes rc.2, and beta becomes beta.1).
+func bumpPrerelease(prerelease string) string {
+	identifiers := strings.Split(prerelease, ".")
+	last := len(identifiers) - 1
+	if n, err := strconv.Atoi(identifiers[last]); err == nil {
+		identifiers[last] = strconv.Itoa(n + 1)
+		return strings.Join(identifiers, ".")
+	}
+	return prerelease + ".1"
+}
+
+// replaceTagVersion replaces the version in the given tag with the new version. When the tag filter has a capture
+// group, the capture group is replaced. Otherwise, the whole tag is replaced, keeping any v prefix.
+func replaceTagVersion(tag string, tagFilter *regexp.Regexp, newVersion string) string {
+	if tagFilter != nil && tagFilter.NumSubexp() > 0 {
+		loc := tagFilter.FindStringSubmatchIndex(tag)
+		if loc != nil && loc[2] >= 0 {
+			return tag[:loc[2]] + newVersion + tag[loc[3]:]
+		}
+	}
+	if strings.HasPrefix(tag, "v") || strings.HasPrefix(tag, "V") {
+		return tag[:1] + newVersion
+	}
+	return newVersion
+}
@@ -0,0 +1,104 @@
+package gitea
+
+import (
+	"regexp"
+	"testing"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/hashicorp/go-version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBumpVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		version  string
+		level    BumpLevel
+		expected string
+	}{
+		{"1.2.3", BumpMajor, "2.0.0"},
+		{"1.2.3", BumpMinor, "1.3.0"},
+		{"1.2.3", BumpPatch, "1.2.4"},
+		{"1.2.3", BumpPrerelease, "1.2.4-rc.1"},
+		{"1.2.3+build.5", BumpPatch, "1.2.4"},
+		{"1.2.4-rc.1", BumpPrerelease, "1.2.4-rc.2"},
+		{"1.2.4-beta", BumpPrerelease, "1.2.4-beta.1"},
+		{"1.2.4-rc.1", BumpPatch, "1.2.4"},
+		{"1.2.4-rc.1", BumpMinor, "1.3.0"},
+		{"1.3.0-rc.1", BumpMinor, "1.3.0"},
+		{"1.3.0-rc.1", BumpMajor, "2.0.0"},
+		{"2.0.0-rc.1", BumpMajor, "2.0.0"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.version+"/"+string(tc.level), func(t *testing.T) {
+			t.Parallel()
+
+			v, err := version.NewVersion(tc.version)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, BumpVersion(v, tc.level))
+		})
+	}
+}
+
+func TestNextReleaseTag(t *testing.T) {
+	t.Parallel()
+
+	releases := func(tags ...string) []*gitea.Release {
+		out := []*gitea.Release{}
+		for _, tag := range tags {
+			out = append(out, &gitea.Release{TagName: tag})
+		}
+		return out
+	}
+
+	testCases := []struct {
+		name      string
+		releases  []*gitea.Release
+		tagFilter string
+		level     BumpLevel
+		expected  string
+	}{
+		{"HighestVersion", releases("v1.2.0", "v1.10.0", "v1.9.3"), "", BumpPatch, "v1.10.1"},
+		{"NoPrefix", releases("1.2.0", "not-semver"), "", BumpMinor, "1.3.0"},
+		{"Prerelease", releases("v1.2.0", "v1.2.1-rc.1"), "", BumpPrerelease, "v1.2.1-rc.2"},
+		{"NoReleases", releases(), "", BumpMinor, "v0.1.0"},
+		{"TagFilter", releases("release-2024.5.1", "v9.9.9"), "^release-(.+)$", BumpPatch, "release-2024.5.2"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var tagFilter *regexp.Regexp
+			if tc.tagFilter != "" {
+				tagFilter = regexp.MustCompile(tc.tagFilter)
+			}
+			tag, err := NextReleaseTag(tc.releases, tagFilter, tc.level)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, tag)
+		})
+	}
+
+	_, err := NextReleaseTag(releases(), regexp.MustCompile("^release-(.+)$"), BumpPatch)
+	assert.Error(t, err)
+}
+
+func TestNewBumpLevel(t *testing.T) {
+	t.Parallel()
+
+	level, err := NewBumpLevel("minor")
+	require.NoError(t, err)
+	assert.Equal(t, BumpMinor, level)
+
+	_, err = NewBumpLevel("")
+	assert.Error(t, err)
+	_, err = NewBumpLevel("micro")
+	assert.Error(t, err)
+}
@@ -10,9 +10,14 @@ import (
 	"code.gitea.io/sdk/gitea"
 )
 
-// NewGiteaClient returns an authenticated gitea API client for the given server URL.
-func NewGiteaClient(serverURL, accessToken string) (*gitea.Client, error) {
-	return gitea.NewClient(serverURL, gitea.SetToken(accessToken))
+// NewGiteaClient returns an authenticated gitea API client for the given server URL. When httpClt is not nil, it is used
+// to make the API requests, which allows configuring the TLS settings for talking to the server.
+func NewGiteaClient(serverURL, accessToken string, httpClt *http.Client) (*gitea.Client, error) {
+	opts := []gitea.ClientOption{gitea.SetToken(accessToken)}
+	if httpClt != nil {
+		opts = append(opts, gitea.SetHTTPClient(httpClt))
+	}
+	return gitea.NewClient(serverURL, opts...)
 }
 
 // pageLinks is a struct representing the pagination links header in a Gitea API response.
@@ -0,0 +1,225 @@
+package gitea
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	gohttp "net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+// DefaultReleaseNotesTemplate is the text/template used to render generated release notes when no template is
+// provided. It lists the subject line of every commit since the previous release.
+const DefaultReleaseNotesTemplate = `{{ if .PreviousTag }}## Changes since {{ .PreviousTag }}{{ else }}## Changes{{ end }}
+{{ range .Commits }}
+- {{ .Subject }} ({{ .ShortSHA }}){{ end }}
+`
+
+// shortSHALen is the length of the abbreviated commit SHAs in release notes, matching the Gitea UI.
+const shortSHALen = 10
+
+// ReleaseNotesData is the data that release notes templates are rendered with.
+type ReleaseNotesData struct {
+	// Tag is the tag of the release that the notes are generated for.
+	Tag string
+	// Target is the git ref that the release points to.
+	Target string
+	// PreviousTag is the tag of the previous release, or empty if there is no previous release.
+	PreviousTag string
+	// Commits are the commits between the previous release and the target, newest first.
+	Commits []ReleaseNotesCommit
+}
+
+// ReleaseNotesCommit describes a single commit in the release notes.
+type ReleaseNotesCommit struct {
+	SHA      string
+	ShortSHA string
+	// Subject is the first line of the commit message.
+	Subject string
+	Message string
+	Author  string
+	URL     string
+}
+
+// PreviousRelease returns the newest of the given releases that is older than the current release, according to the
+// order in the given options. The releases are expected to be sorted from newest to oldest, as returned by
+// GetReleases. Returns nil if there is no older release.
+func PreviousRelease(releases []*gitea.Release, current *gitea.Release, opts ListReleaseOpts) *gitea.Release {
+	newerThan := releaseComparator(opts)
+	for _, release := range releases {
+		if release.TagName != current.TagName && newerThan(current, release) {
+			return release
+		}
+	}
+	return nil
+}
+
+// GetReleaseNotesData returns the data for rendering the release notes of the release with the given tag. When
+// previousTag is set, the commits are the ones that are reachable from target but not from previousTag, as returned by
+// the compare API of Gitea (so that the previous tag doesn't need to be an ancestor of target, as with release
+// branches). Otherwise, all the commits reachable from target are included. The compare API is not wrapped by the Gitea
+// SDK, so it is called with the given HTTP client, which must be authenticated for private repositories. On Gitea
+// versions before 1.22, which don't have the compare API, the commits of both refs are listed and compared instead.
+func GetReleaseNotesData(
+	clt *gitea.Client,
+	httpClt *gohttp.Client,
+	serverURL, owner, repo, tag, target, previousTag string,
+) (*ReleaseNotesData, error) {
+	out := &ReleaseNotesData{
+		Tag:         tag,
+		Target:      target,
+		PreviousTag: previousTag,
+		Commits:     []ReleaseNotesCommit{},
+	}
+
+	var commits []*gitea.Commit
+	var err error
+	if previousTag != "" {
+		commits, err = compareCommits(httpClt, serverURL, owner, repo, previousTag, target)
+		if errors.Is(err, errCompareNotFound) {
+			commits, err = listCommitsBetween(clt, owner, repo, previousTag, target)
+		}
+	} else {
+		commits, err = listCommits(clt, owner, repo, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, commit := range commits {
+		if commit.CommitMeta == nil {
+			continue
+		}
+		out.Commits = append(out.Commits, newReleaseNotesCommit(commit))
+	}
+	return out, nil
+}
+
+// errCompareNotFound is returned by compareCommits when the compare API responds with 404 Not Found, either because
+// the server doesn't have the compare API, or because one of the refs doesn't exist.
+var errCompareNotFound = errors.New("compare API returned 404 Not Found")
+
+// compareResponse is the subset of the response of the Gitea compare API that is used for release notes.
+type compareResponse struct {
+	Commits []*gitea.Commit `json:"commits"`
+}
+
+// compareCommits returns the commits that are reachable from head but not from base, newest first, using the
+// /repos/{owner}/{repo}/compare/{base}...{head} API.
+func compareCommits(httpClt *gohttp.Client, serverURL, owner, repo, base, head string) ([]*gitea.Commit, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	u = u.JoinPath("api", "v1", "repos", owner, repo, "compare", base+"..."+head)
+
+	resp, err := httpClt.Get(u.String())
+	if err != nil {
+		return nil, fmt.Errorf("error comparing %s with %s: %w", head, base, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == gohttp.StatusNotFound {
+		return nil, fmt.Errorf("error comparing %s with %s: %w", head, base, errCompareNotFound)
+	}
+	if resp.StatusCode != gohttp.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf(
+			"error comparing %s with %s: unexpected status %s: %s", head, base, resp.Status, strings.TrimSpace(string(msg)),
+		)
+	}
+
+	var out compareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("error reading comparison of %s with %s: %w", head, base, err)
+	}
+	return out.Commits, nil
+}
+
+// listCommitsBetween returns the commits that are reachable from head but not from base, newest first, by listing the
+// commits of both refs. This is slower than the compare API, since the whole history of base is listed.
+func listCommitsBetween(clt *gitea.Client, owner, repo, base, head string) ([]*gitea.Commit, error) {
+	baseCommits, err := listCommits(clt, owner, repo, base)
+	if err != nil {
+		return nil, err
+	}
+	reachableFromBase := map[string]bool{}
+	for _, commit := range baseCommits {
+		if commit.CommitMeta != nil {
+			reachableFromBase[commit.SHA] = true
+		}
+	}
+
+	headCommits, err := listCommits(clt, owner, repo, head)
+	if err != nil {
+		return nil, err
+	}
+	out := []*gitea.Commit{}
+	for _, commit := range headCommits {
+		if commit.CommitMeta != nil && !reachableFromBase[commit.SHA] {
+			out = append(out, commit)
+		}
+	}
+	return out, nil
+}
+
+// listCommits returns all the commits that are reachable from the given ref, newest first.
+func listCommits(clt *gitea.Client, owner, repo, ref string) ([]*gitea.Commit, error) {
+	out := []*gitea.Commit{}
+	for page := 1; ; page++ {
+		commits, resp, err := clt.ListRepoCommits(owner, repo, gitea.ListCommitOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: defaultPageSize},
+			SHA:         ref,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing commits of %s: %w", ref, err)
+		}
+		out = append(out, commits...)
+
+		if len(commits) == 0 || !hasNextPage(resp) {
+			return out, nil
+		}
+	}
+}
+
+// RenderReleaseNotes renders the given text/template with the release notes data. The DefaultReleaseNotesTemplate is
+// used when the template is empty.
+func RenderReleaseNotes(tmplStr string, data *ReleaseNotesData) (string, error) {
+	if tmplStr == "" {
+		tmplStr = DefaultReleaseNotesTemplate
+	}
+	tmpl, err := template.New("notes").Option("missingkey=error").Parse(tmplStr)
+	if err != nil {
+		return "", fmt.Errorf("error parsing release notes template: %w", err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("error rendering release notes template: %w", err)
+	}
+	return out.String(), nil
+}
+
+func newReleaseNotesCommit(commit *gitea.Commit) ReleaseNotesCommit {
+	out := ReleaseNotesCommit{
+		SHA:      commit.SHA,
+		ShortSHA: commit.SHA,
+		URL:      commit.HTMLURL,
+	}
+	if len(out.ShortSHA) > shortSHALen {
+		out.ShortSHA = out.ShortSHA[:shortSHALen]
+	}
+	if commit.RepoCommit != nil {
+		out.Message = strings.TrimSpace(commit.RepoCommit.Message)
+		out.Subject, _, _ = strings.Cut(out.Message, "\n")
+		if commit.RepoCommit.Author != nil {
+			out.Author = commit.RepoCommit.Author.Name
+		}
+	}
+	return out
+}
@@ -0,0 +1,160 @@
+package gitea
+
+import (
+	gohttp "net/http"
+	"testing"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yorinasub17/concourse-gitea-release-resource/test"
+)
+
+func TestGetReleaseNotesData(t *testing.T) {
+	t.Parallel()
+	testGetReleaseNotesData(t, serverURL)
+}
+
+func TestGetReleaseNotesDataWithoutCompareAPI(t *testing.T) {
+	t.Parallel()
+	if test.UseLiveServer() {
+		t.Skip("the compare API can only be disabled on the fake server")
+	}
+
+	srv, err := test.NewFakeServer()
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	srv.DisableCompareAPI()
+
+	testGetReleaseNotesData(t, srv.URL)
+}
+
+func testGetReleaseNotesData(t *testing.T, serverURL string) {
+	clt, err := gitea.NewClient(serverURL, gitea.SetBasicAuth(test.Username, test.Password))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name            string
+		target          string
+		previousTag     string
+		expectedSubject []string
+	}{
+		{"BetweenTags", "v0.0.1", "v0.0.0", []string{"random file", "random file"}},
+		{"SameTag", "v0.0.1", "v0.0.1", []string{}},
+		{"NoPreviousTag", "v0.0.0-alpha.1", "", []string{"random file", "initial commit"}},
+		{"PreviousTagNotAncestor", "v0.0.0-alpha.1", "v0.0.1", []string{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// The repository is public, so the compare API doesn't need an authenticated HTTP client.
+			data, err := GetReleaseNotesData(
+				clt, gohttp.DefaultClient, serverURL, test.Username, test.PublicRepo, "next", tc.target, tc.previousTag,
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tc.previousTag, data.PreviousTag)
+
+			subjects := []string{}
+			for _, commit := range data.Commits {
+				subjects = append(subjects, commit.Subject)
+				assert.Len(t, commit.ShortSHA, shortSHALen)
+			}
+			assert.Equal(t, tc.expectedSubject, subjects)
+		})
+	}
+
+	_, err = GetReleaseNotesData(
+		clt, gohttp.DefaultClient, serverURL, test.Username, test.PublicRepo, "next", "v0.0.1", "does-not-exist",
+	)
+	assert.Error(t, err)
+}
+
+func TestPreviousRelease(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	releases := []*gitea.Release{
+		{TagName: "v1.2.0", PublishedAt: now},
+		{TagName: "v1.1.0", PublishedAt: now.Add(-time.Hour)},
+		{TagName: "v1.0.0", PublishedAt: now.Add(-2 * time.Hour)},
+	}
+	opts := ListReleaseOpts{OrderBy: OrderBySemver}
+
+	testCases := []struct {
+		name        string
+		currentTag  string
+		expectedTag string
+	}{
+		{"Newest", "v1.3.0", "v1.2.0"},
+		{"Backport", "v1.1.1", "v1.1.0"},
+		{"Existing", "v1.1.0", "v1.0.0"},
+		{"Oldest", "v0.9.0", ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			prev := PreviousRelease(releases, &gitea.Release{TagName: tc.currentTag, PublishedAt: now}, opts)
+			if tc.expectedTag == "" {
+				assert.Nil(t, prev)
+			} else {
+				require.NotNil(t, prev)
+				assert.Equal(t, tc.expectedTag, prev.TagName)
+			}
+		})
+	}
+}
+
+func TestRenderReleaseNotes(t *testing.T) {
+	t.Parallel()
+
+	data := &ReleaseNotesData{
+		Tag:         "v1.1.0",
+		PreviousTag: "v1.0.0",
+		Commits: []ReleaseNotesCommit{
+			{SHA: "abcdef1234567890", ShortSHA: "abcdef1234", Subject: "Add feature", Author: "alice"},
+			{SHA: "1234567890abcdef", ShortSHA: "1234567890", Subject: "Fix bug", Author: "bob"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		tmpl     string
+		expected string
+	}{
+		{
+			"Default",
+			"",
+			"## Changes since v1.0.0\n\n- Add feature (abcdef1234)\n- Fix bug (1234567890)\n",
+		},
+		{
+			"Custom",
+			"{{ .Tag }}:{{ range .Commits }} {{ .Author }}{{ end }}",
+			"v1.1.0: alice bob",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			notes, err := RenderReleaseNotes(tc.tmpl, data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, notes)
+		})
+	}
+
+	_, err := RenderReleaseNotes("{{ .Unknown }}", data)
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package gitea
+
+import (
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// DefaultParallelism is the number of concurrent workers to use for transferring release assets when no parallelism is
+// configured.
+const DefaultParallelism = 1
+
+// forEachParallel calls fn for each index in [0, n) using a pool of at most parallelism concurrent workers, and waits
+// for all the calls to finish. A parallelism of less than 1 is treated as DefaultParallelism. The errors returned by fn
+// are aggregated in index order regardless of the order the calls finish in, so that the output is deterministic.
+func forEachParallel(n, parallelism int, fn func(i int) error) error {
+	if parallelism < 1 {
+		parallelism = DefaultParallelism
+	}
+	if parallelism > n {
+		parallelism = n
+	}
+
+	errs := make([]error, n)
+	indices := make(chan int)
+	wg := new(sync.WaitGroup)
+	wg.Add(parallelism)
+	for w := 0; w < parallelism; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	var allErr error
+	for _, err := range errs {
+		if err != nil {
+			allErr = multierror.Append(allErr, err)
+		}
+	}
+	return allErr
+}
@@ -0,0 +1,77 @@
+package gitea
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachParallel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                string
+		parallelism         int
+		expectedMaxInFlight int32
+	}{
+		{"Sequential", 1, 1},
+		{"DefaultsWhenUnset", 0, DefaultParallelism},
+		{"Bounded", 3, 3},
+		{"MoreWorkersThanItems", 20, 10},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var inFlight, maxInFlight int32
+			called := make([]bool, 10)
+			err := forEachParallel(len(called), tc.parallelism, func(i int) error {
+				current := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					max := atomic.LoadInt32(&maxInFlight)
+					if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+						break
+					}
+				}
+
+				// Finish the later items first to make sure the errors are still reported in index order.
+				time.Sleep(time.Duration(len(called)-i) * time.Millisecond)
+				called[i] = true
+				if i%3 == 0 {
+					return fmt.Errorf("error %d", i)
+				}
+				return nil
+			})
+
+			for i, c := range called {
+				assert.Truef(t, c, "item %d was not processed", i)
+			}
+			assert.LessOrEqual(t, maxInFlight, tc.expectedMaxInFlight)
+
+			require.Error(t, err)
+			merr, ok := err.(*multierror.Error)
+			require.True(t, ok)
+			var errStrs []string
+			for _, e := range merr.Errors {
+				errStrs = append(errStrs, e.Error())
+			}
+			assert.Equal(t, []string{"error 0", "error 3", "error 6", "error 9"}, errStrs)
+		})
+	}
+}
+
+func TestForEachParallelNoErrors(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, forEachParallel(5, 2, func(int) error { return nil }))
+	assert.NoError(t, forEachParallel(0, 2, func(int) error { return nil }))
+}
@@ -1,19 +1,100 @@
 package gitea
 
 import (
+	"errors"
+	"fmt"
+	gohttp "net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
 	"code.gitea.io/sdk/gitea"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-version"
 
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/checksum"
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/http"
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/signature"
 )
 
 var defaultPageSize = 100
 
+// ReleaseOrder is the strategy to use for ordering the releases returned by GetReleases.
+type ReleaseOrder string
+
+const (
+	// OrderByGitea keeps the order returned by the Gitea API.
+	OrderByGitea ReleaseOrder = "gitea"
+	// OrderBySemver orders the releases by the semantic version of the tag (see ReleaseVersion). Releases with tags that
+	// are not valid semver are ordered as older than all other releases.
+	OrderBySemver ReleaseOrder = "semver"
+	// OrderByPublishedAt orders the releases by the time they were published.
+	OrderByPublishedAt ReleaseOrder = "published_at"
+	// OrderByCreatedAt orders the releases by the time they were created.
+	OrderByCreatedAt ReleaseOrder = "created_at"
+)
+
+// DraftFilter is the strategy for handling draft releases when querying for releases.
+type DraftFilter string
+
+const (
+	// ExcludeDrafts excludes draft releases from the query.
+	ExcludeDrafts DraftFilter = "exclude"
+	// IncludeDrafts includes draft releases alongside published releases in the query.
+	IncludeDrafts DraftFilter = "include"
+	// OnlyDrafts only includes draft releases in the query.
+	OnlyDrafts DraftFilter = "only"
+)
+
+// CheckMode is the strategy for choosing the releases that check emits as versions. The empty CheckMode emits the
+// newest release on the first check, and every newer release than the current version on the subsequent checks.
+type CheckMode string
+
+const (
+	// CheckLatest always emits only the newest release, so that pipelines skip to it.
+	CheckLatest CheckMode = "latest"
+	// CheckEvery emits every release, starting from the oldest on the first check, so that pipelines process every
+	// release in order.
+	CheckEvery CheckMode = "every"
+)
+
+// MissingVersionPolicy is the strategy check uses when the release of the version it is given was deleted, or its tag
+// was changed.
+type MissingVersionPolicy string
+
+const (
+	// MissingVersionLatest emits the newest release, as if check was not given a version.
+	MissingVersionLatest MissingVersionPolicy = "latest"
+	// MissingVersionFail fails the check.
+	MissingVersionFail MissingVersionPolicy = "fail"
+	// MissingVersionEmpty emits no versions.
+	MissingVersionEmpty MissingVersionPolicy = "empty"
+)
+
+// ErrMissingVersion is returned by GetVersionRelease when the release of the version was deleted, or its tag was
+// changed.
+var ErrMissingVersion = errors.New("missing version")
+
+// AssetConflictStrategy is the strategy for handling assets that have the same name as an existing asset on the release
+// when uploading.
+type AssetConflictStrategy string
+
+const (
+	// ReplaceConflictingAssets deletes the existing asset before uploading the new one.
+	ReplaceConflictingAssets AssetConflictStrategy = "replace"
+	// SkipConflictingAssets leaves the existing asset as is and does not upload the new one.
+	SkipConflictingAssets AssetConflictStrategy = "skip"
+	// FailOnConflictingAssets returns an error without uploading any assets.
+	FailOnConflictingAssets AssetConflictStrategy = "fail"
+	// KeepConflictingAssets uploads the new asset alongside the existing one, resulting in multiple assets with the same
+	// name.
+	KeepConflictingAssets AssetConflictStrategy = "keep_both"
+)
+
 // ListReleaseOpts is a struct representing the filters to apply when querying for releases.
 type ListReleaseOpts struct {
 	// Owner is the owner of the repository in the target Gitea instance.
@@ -23,8 +104,29 @@ type ListReleaseOpts struct {
 	// SemverConstraint is a semantic versioning constraint in the same syntax as Terraform. Refer to
 	// https://www.terraform.io/language/expressions/version-constraints for supported syntax.
 	SemverConstraint version.Constraints
+	// TagFilter is a regular expression that release tags must match. If the expression has a capture group, the first
+	// group is used as the version portion of the tag when applying SemverConstraint and ordering by semver.
+	TagFilter *regexp.Regexp
+	// TargetCommitish is a glob that the target of the releases (the branch or commit that the release was cut from)
+	// must match, in the syntax of path.Match. All targets are included when empty.
+	TargetCommitish string
+	// TitleFilter is a regular expression that the release titles must match.
+	TitleFilter *regexp.Regexp
+	// BodyFilter is a regular expression that the release notes must match.
+	BodyFilter *regexp.Regexp
+	// RequiredAssets are globs that must each match at least one asset of the release, in the syntax of filepath.Match.
+	// This can be used to ignore releases until all their assets are uploaded.
+	RequiredAssets []string
+	// ReleaseAuthors are the usernames of the users that publish the releases. Releases published by any user are
+	// included when empty.
+	ReleaseAuthors []string
 	// IncludePreRelease indicates if pre releases should be included in the query.
 	IncludePreRelease bool
+	// Drafts indicates how draft releases should be handled in the query. Defaults to excluding drafts when empty. Note
+	// that Gitea only returns draft releases to users with write access to the repository.
+	Drafts DraftFilter
+	// OrderBy is the strategy to use for ordering the returned releases. Defaults to the Gitea order when empty.
+	OrderBy ReleaseOrder
 }
 
 // CreateReleaseOpts is a struct representing the metadata for creating a new release.
@@ -43,11 +145,17 @@ type CreateReleaseOpts struct {
 	Body string
 	// IsPreRelease indicates whether the new release should be marked as a prerelease.
 	IsPreRelease bool
+	// IsDraft indicates whether the new release should be a draft, which is not visible to the public.
+	IsDraft bool
 }
 
 // NewListReleaseOpts constructs a new ListReleaseOpts filter based on the provided raw values.
 func NewListReleaseOpts(owner, repo, semverConstraintStr string, includePreRelease bool) (*ListReleaseOpts, error) {
-	out := &ListReleaseOpts{owner, repo, nil, includePreRelease}
+	out := &ListReleaseOpts{
+		Owner:             owner,
+		Repo:              repo,
+		IncludePreRelease: includePreRelease,
+	}
 
 	if semverConstraintStr != "" {
 		semverConstraint, err := version.NewConstraint(semverConstraintStr)
@@ -60,6 +168,108 @@ func NewListReleaseOpts(owner, repo, semverConstraintStr string, includePreRelea
 	return out, nil
 }
 
+// NewReleaseOrder validates and returns the ReleaseOrder for the given raw string. Defaults to OrderByGitea when the
+// string is empty.
+func NewReleaseOrder(orderByStr string) (ReleaseOrder, error) {
+	switch order := ReleaseOrder(orderByStr); order {
+	case "":
+		return OrderByGitea, nil
+	case OrderByGitea, OrderBySemver, OrderByPublishedAt, OrderByCreatedAt:
+		return order, nil
+	}
+	return "", fmt.Errorf(
+		"unknown release order %q: must be one of %s, %s, %s, or %s",
+		orderByStr, OrderBySemver, OrderByPublishedAt, OrderByCreatedAt, OrderByGitea,
+	)
+}
+
+// NewDraftFilter validates and returns the DraftFilter for the given raw string. Defaults to ExcludeDrafts when the
+// string is empty.
+func NewDraftFilter(draftsStr string) (DraftFilter, error) {
+	switch filter := DraftFilter(draftsStr); filter {
+	case "":
+		return ExcludeDrafts, nil
+	case ExcludeDrafts, IncludeDrafts, OnlyDrafts:
+		return filter, nil
+	}
+	return "", fmt.Errorf(
+		"unknown drafts mode %q: must be one of %s, %s, or %s",
+		draftsStr, ExcludeDrafts, IncludeDrafts, OnlyDrafts,
+	)
+}
+
+// NewTargetCommitishFilter validates and returns the glob for filtering releases by their target.
+func NewTargetCommitishFilter(glob string) (string, error) {
+	if _, err := path.Match(glob, ""); err != nil {
+		return "", fmt.Errorf("invalid target commitish glob %q: %w", glob, err)
+	}
+	return glob, nil
+}
+
+// NewRequiredAssetsFilter validates and returns the globs for filtering releases by their assets.
+func NewRequiredAssetsFilter(globs []string) ([]string, error) {
+	for _, glob := range globs {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid required asset glob %q: %w", glob, err)
+		}
+	}
+	return globs, nil
+}
+
+// NewCheckMode validates and returns the CheckMode for the given raw string. The empty string is returned as is, for the
+// default check behavior.
+func NewCheckMode(modeStr string) (CheckMode, error) {
+	switch mode := CheckMode(modeStr); mode {
+	case "", CheckLatest, CheckEvery:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown check mode %q: must be one of %s or %s", modeStr, CheckLatest, CheckEvery)
+}
+
+// NewMissingVersionPolicy validates and returns the MissingVersionPolicy for the given raw string. Defaults to
+// MissingVersionLatest when the string is empty.
+func NewMissingVersionPolicy(policyStr string) (MissingVersionPolicy, error) {
+	switch policy := MissingVersionPolicy(policyStr); policy {
+	case "":
+		return MissingVersionLatest, nil
+	case MissingVersionLatest, MissingVersionFail, MissingVersionEmpty:
+		return policy, nil
+	}
+	return "", fmt.Errorf(
+		"unknown missing version policy %q: must be one of %s, %s, or %s",
+		policyStr, MissingVersionLatest, MissingVersionFail, MissingVersionEmpty,
+	)
+}
+
+// NewAssetConflictStrategy validates and returns the AssetConflictStrategy for the given raw string. Defaults to
+// KeepConflictingAssets when the string is empty.
+func NewAssetConflictStrategy(strategyStr string) (AssetConflictStrategy, error) {
+	switch strategy := AssetConflictStrategy(strategyStr); strategy {
+	case "":
+		return KeepConflictingAssets, nil
+	case ReplaceConflictingAssets, SkipConflictingAssets, FailOnConflictingAssets, KeepConflictingAssets:
+		return strategy, nil
+	}
+	return "", fmt.Errorf(
+		"unknown asset conflict strategy %q: must be one of %s, %s, %s, or %s",
+		strategyStr, ReplaceConflictingAssets, SkipConflictingAssets, FailOnConflictingAssets, KeepConflictingAssets,
+	)
+}
+
+// ReleaseVersion returns the semantic version of the given release tag. When the tag filter has a capture group, the
+// version is parsed from the first capture group instead of the whole tag.
+func ReleaseVersion(tag string, tagFilter *regexp.Regexp) (*version.Version, error) {
+	versionStr := tag
+	if tagFilter != nil && tagFilter.NumSubexp() > 0 {
+		matches := tagFilter.FindStringSubmatch(tag)
+		if matches == nil {
+			return nil, fmt.Errorf("tag %s does not match tag filter %s", tag, tagFilter)
+		}
+		versionStr = matches[1]
+	}
+	return version.NewVersion(versionStr)
+}
+
 // GetReleaseByID returns the corresponding release for the given ID string.
 func GetReleaseByID(clt *gitea.Client, owner, repo, releaseIDStr string) (*gitea.Release, error) {
 	releaseID, err := strconv.ParseInt(releaseIDStr, 10, 64)
@@ -77,8 +287,50 @@ func GetReleaseByTag(clt *gitea.Client, owner, repo, tagName string) (*gitea.Rel
 	return rel, err
 }
 
-// GetReleases returns all the releases that match the provided filter options. This will handle pagination, going
-// through all release pages.
+// TagExists returns whether the given git tag exists in the repository, regardless of whether it has a release.
+func TagExists(clt *gitea.Client, owner, repo, tagName string) (bool, error) {
+	_, resp, err := clt.GetTag(owner, repo, tagName)
+	if isNotFound(resp) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetVersionRelease returns the release for the version with the given release ID and tag, confirming that the release
+// still exists and that its tag was not changed. The release is looked up by tag when the ID is empty. Returns an error
+// wrapping ErrMissingVersion when the release was deleted or re-tagged.
+func GetVersionRelease(clt *gitea.Client, owner, repo, releaseIDStr, tagName string) (*gitea.Release, error) {
+	if releaseIDStr == "" {
+		rel, resp, err := clt.GetReleaseByTag(owner, repo, tagName)
+		if isNotFound(resp) {
+			return nil, fmt.Errorf("%w: release with tag %s does not exist", ErrMissingVersion, tagName)
+		}
+		return rel, err
+	}
+
+	releaseID, err := strconv.ParseInt(releaseIDStr, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	rel, resp, err := clt.GetRelease(owner, repo, releaseID)
+	switch {
+	case isNotFound(resp):
+		return nil, fmt.Errorf("%w: release %d with tag %s does not exist", ErrMissingVersion, releaseID, tagName)
+	case err != nil:
+		return nil, err
+	case rel.TagName != tagName:
+		return nil, fmt.Errorf(
+			"%w: release %d was re-tagged from %s to %s", ErrMissingVersion, releaseID, tagName, rel.TagName,
+		)
+	}
+	return rel, nil
+}
+
+// GetReleases returns all the releases that match the provided filter options, ordered from newest to oldest according
+// to opts.OrderBy. This will handle pagination, going through all release pages.
 func GetReleases(clt *gitea.Client, opts ListReleaseOpts) ([]*gitea.Release, error) {
 	releases, resp, err := getReleasesPageWithFilter(clt, opts, 1)
 	if err != nil {
@@ -100,9 +352,96 @@ func GetReleases(clt *gitea.Client, opts ListReleaseOpts) ([]*gitea.Release, err
 		resp = pagedResp
 	}
 
+	SortReleases(releases, opts)
 	return releases, nil
 }
 
+// SortReleases sorts the given releases in place from newest to oldest, according to the order in the given options.
+// With the Gitea order, releases are sorted by publish time with the release ID as the tie-break, which is the order
+// that Gitea lists releases in. Releases that are equivalent in the other orders keep their relative order from Gitea.
+func SortReleases(releases []*gitea.Release, opts ListReleaseOpts) {
+	newerThan := sortComparator(opts)
+	sort.SliceStable(releases, func(i, j int) bool {
+		return newerThan(releases[i], releases[j])
+	})
+}
+
+// ReleasesSortedBefore returns the releases that SortReleases orders before the current release. Unlike NewerReleases,
+// this is a strict order in the Gitea order, which compares releases by publish time and release ID instead of by
+// semver, so that every release is either before or after the current release.
+func ReleasesSortedBefore(releases []*gitea.Release, current *gitea.Release, opts ListReleaseOpts) []*gitea.Release {
+	newerThan := sortComparator(opts)
+	out := []*gitea.Release{}
+	for _, release := range releases {
+		if newerThan(release, current) {
+			out = append(out, release)
+		}
+	}
+	return out
+}
+
+// NewerReleases returns the releases that are newer than the current release, according to the order in the given
+// options. Since the Gitea order can not be compared directly, releases are compared by semver when using the Gitea
+// order, falling back to the publish time when the tags are not valid semver.
+func NewerReleases(releases []*gitea.Release, current *gitea.Release, opts ListReleaseOpts) []*gitea.Release {
+	newerThan := releaseComparator(opts)
+	out := []*gitea.Release{}
+	for _, release := range releases {
+		if newerThan(release, current) {
+			out = append(out, release)
+		}
+	}
+	return out
+}
+
+// releaseComparator returns a function that reports whether release a is newer than release b in the order configured
+// in the given options.
+func releaseComparator(opts ListReleaseOpts) func(a, b *gitea.Release) bool {
+	switch opts.OrderBy {
+	case OrderBySemver:
+		return func(a, b *gitea.Release) bool {
+			aV, aErr := ReleaseVersion(a.TagName, opts.TagFilter)
+			bV, bErr := ReleaseVersion(b.TagName, opts.TagFilter)
+			if aErr != nil || bErr != nil {
+				// Releases with tags that are not valid semver are considered older than those with valid semver.
+				return aErr == nil && bErr != nil
+			}
+			return aV.GreaterThan(bV)
+		}
+	case OrderByPublishedAt:
+		return func(a, b *gitea.Release) bool { return a.PublishedAt.After(b.PublishedAt) }
+	case OrderByCreatedAt:
+		return func(a, b *gitea.Release) bool { return a.CreatedAt.After(b.CreatedAt) }
+	}
+
+	return func(a, b *gitea.Release) bool {
+		aV, aErr := ReleaseVersion(a.TagName, opts.TagFilter)
+		bV, bErr := ReleaseVersion(b.TagName, opts.TagFilter)
+		if aErr != nil || bErr != nil {
+			return a.PublishedAt.After(b.PublishedAt)
+		}
+		return aV.GreaterThan(bV)
+	}
+}
+
+// sortComparator returns a function that reports whether release a is sorted before release b by SortReleases. This is
+// the same as releaseComparator, except that the Gitea order is compared by publish time and release ID.
+func sortComparator(opts ListReleaseOpts) func(a, b *gitea.Release) bool {
+	if opts.OrderBy == "" || opts.OrderBy == OrderByGitea {
+		return publishedAfter
+	}
+	return releaseComparator(opts)
+}
+
+// publishedAfter reports whether release a was published after release b, using the release ID as the tie-break for
+// releases that were published at the same time.
+func publishedAfter(a, b *gitea.Release) bool {
+	if !a.PublishedAt.Equal(b.PublishedAt) {
+		return a.PublishedAt.After(b.PublishedAt)
+	}
+	return a.ID > b.ID
+}
+
 func getReleasesPageWithFilter(clt *gitea.Client, opts ListReleaseOpts, page int) ([]*gitea.Release, *gitea.Response, error) {
 	apiOpts := gitea.ListReleasesOptions{
 		ListOptions: gitea.ListOptions{Page: page, PageSize: defaultPageSize},
@@ -110,32 +449,115 @@ func getReleasesPageWithFilter(clt *gitea.Client, opts ListReleaseOpts, page int
 	if !opts.IncludePreRelease {
 		apiOpts.IsPreRelease = &opts.IncludePreRelease
 	}
+	switch opts.Drafts {
+	case IncludeDrafts:
+	case OnlyDrafts:
+		isDraft := true
+		apiOpts.IsDraft = &isDraft
+	default:
+		isDraft := false
+		apiOpts.IsDraft = &isDraft
+	}
 	releases, resp, err := clt.ListReleases(opts.Owner, opts.Repo, apiOpts)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	releasesOut := []*gitea.Release{}
-	if len(opts.SemverConstraint) > 0 {
-		// Filter out releases by those that match the given semver constraint.
-		for _, release := range releases {
-			v, err := version.NewVersion(release.TagName)
-			if err != nil {
-				// ignore releases that don't have parsable semver tags
-				continue
-			}
-			// Check against the core version so that versions with modifiers (like '-alpha.1') are also included in the
-			// check.
-			if opts.SemverConstraint.Check(v.Core()) {
-				releasesOut = append(releasesOut, release)
-			}
+	for _, release := range releases {
+		if matchesFilters(release, opts) {
+			releasesOut = append(releasesOut, release)
 		}
-	} else {
-		releasesOut = releases
 	}
 	return releasesOut, resp, nil
 }
 
+// matchesFilters returns whether the given release matches the filters in the options that can not be applied by the
+// Gitea API.
+func matchesFilters(release *gitea.Release, opts ListReleaseOpts) bool {
+	// The draft filter is applied by the API, but is checked again here for older Gitea versions that ignore it.
+	switch opts.Drafts {
+	case IncludeDrafts:
+	case OnlyDrafts:
+		if !release.IsDraft {
+			return false
+		}
+	default:
+		if release.IsDraft {
+			return false
+		}
+	}
+
+	if opts.TagFilter != nil && !opts.TagFilter.MatchString(release.TagName) {
+		return false
+	}
+
+	if opts.TargetCommitish != "" {
+		// The glob is validated by NewTargetCommitishFilter, so the error can be ignored.
+		if matches, _ := path.Match(opts.TargetCommitish, release.Target); !matches {
+			return false
+		}
+	}
+
+	if opts.TitleFilter != nil && !opts.TitleFilter.MatchString(release.Title) {
+		return false
+	}
+
+	if opts.BodyFilter != nil && !opts.BodyFilter.MatchString(release.Note) {
+		return false
+	}
+
+	for _, glob := range opts.RequiredAssets {
+		if !hasMatchingAttachment(release, glob) {
+			return false
+		}
+	}
+
+	if len(opts.ReleaseAuthors) > 0 && !isPublishedBy(release, opts.ReleaseAuthors) {
+		return false
+	}
+
+	if len(opts.SemverConstraint) > 0 {
+		v, err := ReleaseVersion(release.TagName, opts.TagFilter)
+		if err != nil {
+			// ignore releases that don't have parsable semver tags
+			return false
+		}
+		// Check against the core version so that versions with modifiers (like '-alpha.1') are also included in the
+		// check.
+		if !opts.SemverConstraint.Check(v.Core()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasMatchingAttachment returns whether any of the attachments of the release match the given glob.
+func hasMatchingAttachment(release *gitea.Release, glob string) bool {
+	for _, attachment := range release.Attachments {
+		// The glob is validated by NewRequiredAssetsFilter, so the error can be ignored.
+		if matches, _ := filepath.Match(glob, attachment.Name); matches {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublishedBy returns whether the release was published by any of the given users. Like Gitea, usernames are
+// compared case insensitively.
+func isPublishedBy(release *gitea.Release, usernames []string) bool {
+	if release.Publisher == nil {
+		return false
+	}
+	for _, username := range usernames {
+		if strings.EqualFold(release.Publisher.UserName, username) {
+			return true
+		}
+	}
+	return false
+}
+
 func hasNextPage(resp *gitea.Response) bool {
 	if resp == nil {
 		return false
@@ -149,10 +571,107 @@ func hasNextPage(resp *gitea.Response) bool {
 	return linksOutput.nextPage != nil
 }
 
-// DownloadReleaseAssets downloads the associated assets from the given release to the provided destination directory.
-// The release assets to download can be filtered using glob syntax.
-func DownloadReleaseAssets(clt *gitea.Client, release *gitea.Release, destDir string, globs []string) error {
+func isNotFound(resp *gitea.Response) bool {
+	return resp != nil && resp.StatusCode == gohttp.StatusNotFound
+}
+
+// DownloadReleaseAssets downloads the associated assets from the given release to the provided destination directory,
+// using the given HTTP client. The HTTP client must be authenticated to download assets from private repositories. The
+// release assets to download can be filtered using glob syntax. Up to parallelism assets are downloaded concurrently.
+// Assets with the same name are downloaded one after another in the order of the release, so that the last one wins.
+func DownloadReleaseAssets(
+	clt *gitea.Client,
+	httpClt *gohttp.Client,
+	release *gitea.Release,
+	destDir string,
+	globs []string,
+	parallelism int,
+) error {
+	attachments, allErr := filterAttachments(release, globs)
+
+	var names []string
+	attachmentsByName := map[string][]*gitea.Attachment{}
+	for _, attachment := range attachments {
+		if _, seen := attachmentsByName[attachment.Name]; !seen {
+			names = append(names, attachment.Name)
+		}
+		attachmentsByName[attachment.Name] = append(attachmentsByName[attachment.Name], attachment)
+	}
+
+	downloadErr := forEachParallel(len(names), parallelism, func(i int) error {
+		attachmentPath := filepath.Join(destDir, names[i])
+		for _, attachment := range attachmentsByName[names[i]] {
+			err := http.DownloadFileOverHTTP(httpClt, attachment.DownloadURL, attachmentPath, attachment.Size)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if downloadErr != nil {
+		allErr = multierror.Append(allErr, downloadErr)
+	}
+	return allErr
+}
+
+// VerifyReleaseAssetChecksums verifies the assets that were downloaded from the given release to the provided
+// destination directory (see DownloadReleaseAssets) against the checksum manifests (e.g., SHA256SUMS) attached to the
+// release. Every downloaded asset must be listed in all the manifests on the release, and it is an error if the release
+// has no checksum manifest. Checksum manifests and signatures are not checksummed themselves, so they are skipped.
+func VerifyReleaseAssetChecksums(
+	httpClt *gohttp.Client,
+	release *gitea.Release,
+	destDir string,
+	globs []string,
+) error {
+	attachments, allErr := filterAttachments(release, globs)
+	if allErr != nil {
+		return allErr
+	}
+
+	manifestFound := false
+	for _, algorithm := range checksum.Algorithms {
+		manifest := findAttachment(release, algorithm.ManifestName())
+		if manifest == nil {
+			continue
+		}
+		manifestFound = true
+
+		checksums, err := downloadChecksumManifest(httpClt, manifest)
+		if err != nil {
+			return fmt.Errorf("error reading checksum manifest %s: %w", manifest.Name, err)
+		}
+
+		for _, attachment := range attachments {
+			if attachment.ID == manifest.ID || isChecksumManifest(attachment.Name) || signature.IsSignature(attachment.Name) {
+				continue
+			}
+
+			expected, ok := checksums[attachment.Name]
+			if !ok {
+				allErr = multierror.Append(
+					allErr,
+					fmt.Errorf("asset %s is not listed in checksum manifest %s", attachment.Name, manifest.Name),
+				)
+				continue
+			}
+			if err := checksum.VerifyFile(algorithm, filepath.Join(destDir, attachment.Name), expected); err != nil {
+				allErr = multierror.Append(allErr, err)
+			}
+		}
+	}
+
+	if !manifestFound {
+		return fmt.Errorf("release %d has no checksum manifest", release.ID)
+	}
+	return allErr
+}
+
+// filterAttachments returns the attachments of the release that match any of the given globs, or all the attachments
+// if there are no globs.
+func filterAttachments(release *gitea.Release, globs []string) ([]*gitea.Attachment, error) {
 	var allErr error
+	var attachments []*gitea.Attachment
 	for _, attachment := range release.Attachments {
 		var matchFound bool
 		if len(globs) == 0 {
@@ -172,18 +691,165 @@ func DownloadReleaseAssets(clt *gitea.Client, release *gitea.Release, destDir st
 			}
 		}
 
-		if !matchFound {
+		if matchFound {
+			attachments = append(attachments, attachment)
+		}
+	}
+	return attachments, allErr
+}
+
+func findAttachment(release *gitea.Release, name string) *gitea.Attachment {
+	for _, attachment := range release.Attachments {
+		if attachment.Name == name {
+			return attachment
+		}
+	}
+	return nil
+}
+
+func isChecksumManifest(name string) bool {
+	for _, algorithm := range checksum.Algorithms {
+		if name == algorithm.ManifestName() {
+			return true
+		}
+	}
+	return false
+}
+
+func downloadChecksumManifest(httpClt *gohttp.Client, manifest *gitea.Attachment) (map[string]string, error) {
+	data, err := downloadAttachment(httpClt, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return checksum.ParseManifest(data)
+}
+
+// VerifyReleaseAssetSignatures verifies the assets that were downloaded from the given release to the provided
+// destination directory (see DownloadReleaseAssets) using the detached signatures attached to the release. An asset is
+// verified if it has a signature attachment with the same name and a .sig, .asc, or .minisig extension that is valid
+// for one of the keys trusted by the verifier. An asset without its own signature is also verified if it is listed in
+// a checksum manifest (e.g., SHA256SUMS) that has a valid signature, and its checksum matches. It is an error if any
+// downloaded asset can not be verified.
+func VerifyReleaseAssetSignatures(
+	httpClt *gohttp.Client,
+	release *gitea.Release,
+	destDir string,
+	globs []string,
+	verifier *signature.Verifier,
+) error {
+	attachments, allErr := filterAttachments(release, globs)
+	if allErr != nil {
+		return allErr
+	}
+
+	tmpDir, err := os.MkdirTemp("", "signatures-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Collect the checksums from all the signed manifests, so that the assets that are not signed individually can be
+	// verified against them.
+	type signedChecksum struct {
+		algorithm checksum.Algorithm
+		sum       string
+	}
+	signedChecksums := map[string][]signedChecksum{}
+	for _, algorithm := range checksum.Algorithms {
+		manifest := findAttachment(release, algorithm.ManifestName())
+		if manifest == nil || findSignature(release, manifest.Name) == nil {
 			continue
 		}
 
-		attachmentPath := filepath.Join(destDir, attachment.Name)
-		if err := http.DownloadFileOverHTTP(attachment.DownloadURL, attachmentPath); err != nil {
-			allErr = multierror.Append(allErr, err)
+		manifestPath := filepath.Join(tmpDir, manifest.Name)
+		if err := http.DownloadFileOverHTTP(httpClt, manifest.DownloadURL, manifestPath, manifest.Size); err != nil {
+			return fmt.Errorf("error downloading checksum manifest %s: %w", manifest.Name, err)
+		}
+		if err := verifySignature(httpClt, release, verifier, manifestPath); err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(manifestPath)
+		if err != nil {
+			return err
+		}
+		checksums, err := checksum.ParseManifest(data)
+		if err != nil {
+			return fmt.Errorf("error reading checksum manifest %s: %w", manifest.Name, err)
+		}
+		for name, sum := range checksums {
+			signedChecksums[name] = append(signedChecksums[name], signedChecksum{algorithm, sum})
+		}
+	}
+
+	for _, attachment := range attachments {
+		if signature.IsSignature(attachment.Name) {
+			continue
+		}
+
+		assetPath := filepath.Join(destDir, attachment.Name)
+		if findSignature(release, attachment.Name) != nil {
+			if err := verifySignature(httpClt, release, verifier, assetPath); err != nil {
+				allErr = multierror.Append(allErr, err)
+			}
+			continue
+		}
+
+		sums, ok := signedChecksums[attachment.Name]
+		if !ok {
+			allErr = multierror.Append(
+				allErr,
+				fmt.Errorf("asset %s is not signed and is not listed in a signed checksum manifest", attachment.Name),
+			)
+			continue
+		}
+		for _, sum := range sums {
+			if err := checksum.VerifyFile(sum.algorithm, assetPath, sum.sum); err != nil {
+				allErr = multierror.Append(allErr, err)
+			}
 		}
 	}
 	return allErr
 }
 
+// findSignature returns the detached signature attachment for the asset with the given name, if there is one.
+func findSignature(release *gitea.Release, name string) *gitea.Attachment {
+	for _, ext := range signature.Extensions {
+		if sig := findAttachment(release, name+ext); sig != nil {
+			return sig
+		}
+	}
+	return nil
+}
+
+// verifySignature verifies the file at the given path using the signature attached to the release for the asset with
+// the same name.
+func verifySignature(httpClt *gohttp.Client, release *gitea.Release, verifier *signature.Verifier, path string) error {
+	name := filepath.Base(path)
+	sigAttachment := findSignature(release, name)
+	sig, err := downloadAttachment(httpClt, sigAttachment)
+	if err != nil {
+		return fmt.Errorf("error downloading signature %s: %w", sigAttachment.Name, err)
+	}
+	return verifier.VerifyFile(path, sig)
+}
+
+// downloadAttachment downloads the given attachment and returns the contents. Only use this for small attachments,
+// like checksum manifests and signatures.
+func downloadAttachment(httpClt *gohttp.Client, attachment *gitea.Attachment) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, attachment.Name)
+	if err := http.DownloadFileOverHTTP(httpClt, attachment.DownloadURL, path, attachment.Size); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
 // CreateRelease will create a new release with the given parameters.
 func CreateRelease(clt *gitea.Client, opts CreateReleaseOpts) (*gitea.Release, error) {
 	apiOpts := gitea.CreateReleaseOption{
@@ -192,6 +858,7 @@ func CreateRelease(clt *gitea.Client, opts CreateReleaseOpts) (*gitea.Release, e
 		Title:        opts.Title,
 		Note:         opts.Body,
 		IsPrerelease: opts.IsPreRelease,
+		IsDraft:      opts.IsDraft,
 	}
 	rel, _, err := clt.CreateRelease(opts.Owner, opts.Repo, apiOpts)
 	return rel, err
@@ -205,22 +872,144 @@ func UpdateRelease(clt *gitea.Client, id int64, opts CreateReleaseOpts) (*gitea.
 		Title:        opts.Title,
 		Note:         opts.Body,
 		IsPrerelease: &opts.IsPreRelease,
+		IsDraft:      &opts.IsDraft,
 	}
 	rel, _, err := clt.EditRelease(opts.Owner, opts.Repo, id, apiOpts)
 	return rel, err
 }
 
+// PublishRelease will publish the draft release with the given ID, leaving all other attributes of the release as is.
+func PublishRelease(clt *gitea.Client, owner, repo string, id int64) (*gitea.Release, error) {
+	isDraft := false
+	rel, _, err := clt.EditRelease(owner, repo, id, gitea.EditReleaseOption{IsDraft: &isDraft})
+	return rel, err
+}
+
+// UploadReleaseAssets will upload the files at the given paths to the provided release as assets, using the file
+// basenames as the asset names. Files that have the same name as an existing asset on the release, or as another file
+// in the list, are handled according to the given conflict strategy. When the strategy is FailOnConflictingAssets, all
+// the conflicts are detected before any file is uploaded so that the release is left untouched.
+//
+// Up to parallelism files are uploaded concurrently. Files with the same name are always uploaded one after the other
+// in the given order, so that the conflicts between them are resolved deterministically.
+//
+// Returns the assets on the release that correspond to the given files, which includes the existing assets that were
+// kept in place of a file when skipping conflicts.
+func UploadReleaseAssets(
+	clt *gitea.Client,
+	owner, repo string,
+	release *gitea.Release,
+	paths []string,
+	onConflict AssetConflictStrategy,
+	parallelism int,
+) ([]*gitea.Attachment, error) {
+	existing := map[string][]*gitea.Attachment{}
+	for _, attachment := range release.Attachments {
+		existing[attachment.Name] = append(existing[attachment.Name], attachment)
+	}
+
+	var names []string
+	pathsByName := map[string][]string{}
+	for _, path := range paths {
+		basename := filepath.Base(path)
+		if _, seen := pathsByName[basename]; !seen {
+			names = append(names, basename)
+		}
+		pathsByName[basename] = append(pathsByName[basename], path)
+	}
+
+	if onConflict == FailOnConflictingAssets {
+		var allErr error
+		for _, name := range names {
+			if len(existing[name]) > 0 || len(pathsByName[name]) > 1 {
+				allErr = multierror.Append(
+					allErr,
+					fmt.Errorf("asset %s already exists on release %d", name, release.ID),
+				)
+			}
+		}
+		if allErr != nil {
+			return nil, allErr
+		}
+	}
+
+	assetsByName := make([][]*gitea.Attachment, len(names))
+	err := forEachParallel(len(names), parallelism, func(i int) error {
+		name := names[i]
+		conflicts := existing[name]
+		for _, path := range pathsByName[name] {
+			if len(conflicts) > 0 {
+				switch onConflict {
+				case SkipConflictingAssets:
+					assetsByName[i] = append(assetsByName[i], conflicts...)
+					continue
+				case ReplaceConflictingAssets:
+					for _, attachment := range conflicts {
+						if _, err := clt.DeleteReleaseAttachment(owner, repo, release.ID, attachment.ID); err != nil {
+							return fmt.Errorf("error deleting existing asset %s (%d): %w", name, attachment.ID, err)
+						}
+					}
+					conflicts = nil
+				}
+			}
+
+			attachment, err := uploadReleaseAsset(clt, path, owner, repo, release.ID)
+			if err != nil {
+				return fmt.Errorf("error uploading asset %s: %w", path, err)
+			}
+			conflicts = append(conflicts, attachment)
+			assetsByName[i] = append(assetsByName[i], attachment)
+		}
+		return nil
+	})
+
+	var assets []*gitea.Attachment
+	for _, nameAssets := range assetsByName {
+		assets = append(assets, nameAssets...)
+	}
+	return assets, err
+}
+
+// PruneReleaseAssets will delete all the assets on the release with the given ID, except for the provided assets to
+// keep.
+func PruneReleaseAssets(clt *gitea.Client, owner, repo string, releaseID int64, keep []*gitea.Attachment) error {
+	release, _, err := clt.GetRelease(owner, repo, releaseID)
+	if err != nil {
+		return err
+	}
+
+	keepIDs := map[int64]bool{}
+	for _, attachment := range keep {
+		keepIDs[attachment.ID] = true
+	}
+
+	for _, attachment := range release.Attachments {
+		if keepIDs[attachment.ID] {
+			continue
+		}
+		if _, err := clt.DeleteReleaseAttachment(owner, repo, releaseID, attachment.ID); err != nil {
+			return fmt.Errorf("error deleting asset %s (%d): %w", attachment.Name, attachment.ID, err)
+		}
+	}
+	return nil
+}
+
 // UploadReleaseAssetFromPath will upload the file at the given path to the provided release as an asset, using the file
 // basename as the asset name.
 func UploadReleaseAssetFromPath(clt *gitea.Client, path, owner, repo string, releaseID int64) error {
+	_, err := uploadReleaseAsset(clt, path, owner, repo, releaseID)
+	return err
+}
+
+func uploadReleaseAsset(clt *gitea.Client, path, owner, repo string, releaseID int64) (*gitea.Attachment, error) {
 	basename := filepath.Base(path)
 
 	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
-	_, _, uploadErr := clt.CreateReleaseAttachment(owner, repo, releaseID, f, basename)
-	return uploadErr
+	attachment, _, err := clt.CreateReleaseAttachment(owner, repo, releaseID, f, basename)
+	return attachment, err
 }
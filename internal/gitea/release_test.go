@@ -2,8 +2,14 @@ package gitea
 
 import (
 	"fmt"
+	gohttp "net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"code.gitea.io/sdk/gitea"
 	"github.com/stretchr/testify/assert"
@@ -12,10 +18,30 @@ import (
 	"github.com/yorinasub17/concourse-gitea-release-resource/test"
 )
 
+// serverURL is the URL of the Gitea server that the tests run against. This is either the in-memory fake (default) or
+// the live server when the tests are run through scripts/run_test.sh.
+var serverURL = test.ServerURL
+
+func TestMain(m *testing.M) {
+	if test.UseLiveServer() {
+		os.Exit(m.Run())
+	}
+
+	srv, err := test.NewFakeServer()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not start fake gitea server: %s\n", err)
+		os.Exit(1)
+	}
+	serverURL = srv.URL
+	code := m.Run()
+	srv.Close()
+	os.Exit(code)
+}
+
 func TestGetReleases(t *testing.T) {
 	t.Parallel()
 
-	clt, err := gitea.NewClient(test.ServerURL, gitea.SetBasicAuth(test.Username, test.Password))
+	clt, err := gitea.NewClient(serverURL, gitea.SetBasicAuth(test.Username, test.Password))
 	require.NoError(t, err)
 
 	testCases := []struct {
@@ -131,10 +157,101 @@ func TestGetReleases(t *testing.T) {
 	}
 }
 
+func TestMatchesFiltersTargetCommitish(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		glob     string
+		target   string
+		expected bool
+	}{
+		{"", "main", true},
+		{"main", "main", true},
+		{"main", "release/1.x", false},
+		{"release/*", "release/1.x", true},
+		{"release/*", "release/1.x/hotfix", false},
+		{"release/*", "main", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.glob+"/"+tc.target, func(t *testing.T) {
+			t.Parallel()
+
+			glob, err := NewTargetCommitishFilter(tc.glob)
+			require.NoError(t, err)
+			opts := ListReleaseOpts{TargetCommitish: glob}
+			assert.Equal(t, tc.expected, matchesFilters(&gitea.Release{TagName: "v1.0.0", Target: tc.target}, opts))
+		})
+	}
+
+	_, err := NewTargetCommitishFilter("release/[")
+	assert.Error(t, err)
+}
+
+func TestMatchesFiltersReleaseContents(t *testing.T) {
+	t.Parallel()
+
+	release := &gitea.Release{
+		TagName: "v1.0.0",
+		Title:   "Release v1.0.0",
+		Note:    "Changes:\n- Add feature\n",
+		Attachments: []*gitea.Attachment{
+			{Name: "app_linux_amd64.tar.gz"},
+			{Name: "SHA256SUMS"},
+		},
+		Publisher: &gitea.User{UserName: "release-bot"},
+	}
+
+	testCases := []struct {
+		name           string
+		titleRegex     string
+		bodyRegex      string
+		requiredAssets []string
+		releaseAuthors []string
+		expected       bool
+	}{
+		{"NoFilters", "", "", nil, nil, true},
+		{"TitleMatches", "^Release v", "", nil, nil, true},
+		{"TitleDoesNotMatch", "^Nightly", "", nil, nil, false},
+		{"BodyMatches", "", "(?m)^- Add", nil, nil, true},
+		{"BodyDoesNotMatch", "", "DO NOT USE", nil, nil, false},
+		{"AllAssetsPresent", "", "", []string{"*_linux_amd64.tar.gz", "SHA256SUMS"}, nil, true},
+		{"AssetMissing", "", "", []string{"*_linux_amd64.tar.gz", "*_darwin_arm64.tar.gz"}, nil, false},
+		{"AuthorMatches", "", "", nil, []string{"alice", "Release-Bot"}, true},
+		{"AuthorDoesNotMatch", "", "", nil, []string{"alice"}, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := ListReleaseOpts{}
+			if tc.titleRegex != "" {
+				opts.TitleFilter = regexp.MustCompile(tc.titleRegex)
+			}
+			if tc.bodyRegex != "" {
+				opts.BodyFilter = regexp.MustCompile(tc.bodyRegex)
+			}
+			requiredAssets, err := NewRequiredAssetsFilter(tc.requiredAssets)
+			require.NoError(t, err)
+			opts.RequiredAssets = requiredAssets
+			opts.ReleaseAuthors = tc.releaseAuthors
+			assert.Equal(t, tc.expected, matchesFilters(release, opts))
+		})
+	}
+
+	_, err := NewRequiredAssetsFilter([]string{"[linux"})
+	assert.Error(t, err)
+}
+
 func TestGetReleaseByIDAndTag(t *testing.T) {
 	t.Parallel()
 
-	clt, err := gitea.NewClient(test.ServerURL, gitea.SetBasicAuth(test.Username, test.Password))
+	clt, err := gitea.NewClient(serverURL, gitea.SetBasicAuth(test.Username, test.Password))
 	require.NoError(t, err)
 
 	relByTag, err := GetReleaseByTag(clt, test.Username, test.PublicRepo, "v0.0.1")
@@ -146,6 +263,46 @@ func TestGetReleaseByIDAndTag(t *testing.T) {
 	assert.Equal(t, *relByID, *relByTag)
 }
 
+func TestGetVersionRelease(t *testing.T) {
+	t.Parallel()
+
+	clt, err := gitea.NewClient(serverURL, gitea.SetBasicAuth(test.Username, test.Password))
+	require.NoError(t, err)
+
+	rel, err := GetReleaseByTag(clt, test.Username, test.PublicRepo, "v0.0.1")
+	require.NoError(t, err)
+	releaseID := fmt.Sprintf("%d", rel.ID)
+
+	testCases := []struct {
+		name          string
+		releaseID     string
+		tag           string
+		expectMissing bool
+	}{
+		{"ByID", releaseID, "v0.0.1", false},
+		{"ByTag", "", "v0.0.1", false},
+		{"Deleted", "999999", "v0.0.1", true},
+		{"DeletedTag", "", "v9.9.9", true},
+		{"Retagged", releaseID, "v0.0.1-old", true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			versionRel, err := GetVersionRelease(clt, test.Username, test.PublicRepo, tc.releaseID, tc.tag)
+			if tc.expectMissing {
+				assert.ErrorIs(t, err, ErrMissingVersion)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, rel.ID, versionRel.ID)
+		})
+	}
+}
+
 func TestGetReleasesWithPagination(t *testing.T) {
 	// This test is intentionally not run in parallel due to the page size adjustment which slows down the other tests.
 	defer func() {
@@ -153,7 +310,7 @@ func TestGetReleasesWithPagination(t *testing.T) {
 	}()
 	defaultPageSize = 1
 
-	clt, err := gitea.NewClient(test.ServerURL, gitea.SetBasicAuth(test.Username, test.Password))
+	clt, err := gitea.NewClient(serverURL, gitea.SetBasicAuth(test.Username, test.Password))
 	require.NoError(t, err)
 
 	opts, err := NewListReleaseOpts(test.Username, test.PublicRepo, "", true)
@@ -168,3 +325,249 @@ func TestGetReleasesWithPagination(t *testing.T) {
 	sort.Strings(tags)
 	assert.Equal(t, []string{"v0.0.0", "v0.0.0-alpha.1", "v0.0.1", "v0.0.1-alpha.1"}, tags)
 }
+
+func TestDownloadReleaseAssetsWithDuplicateNames(t *testing.T) {
+	t.Parallel()
+
+	clt, err := gitea.NewClient(serverURL, gitea.SetBasicAuth(test.Username, test.Password))
+	require.NoError(t, err)
+
+	tag := fmt.Sprintf("duplicate-assets-%d", time.Now().UnixNano())
+	rel, _, err := clt.CreateRelease(test.Username, test.EmptyRepo, gitea.CreateReleaseOption{
+		TagName: tag,
+		Target:  "master",
+		Title:   tag,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := clt.DeleteRelease(test.Username, test.EmptyRepo, rel.ID)
+		assert.NoError(t, err)
+	})
+
+	// Assets of different sizes with the same name, as created by the keep_both asset conflict policy.
+	var lastContents string
+	for i := 1; i <= 5; i++ {
+		lastContents = strings.Repeat(fmt.Sprintf("asset %d\n", i), i*1000)
+		_, _, err := clt.CreateReleaseAttachment(
+			test.Username, test.EmptyRepo, rel.ID, strings.NewReader(lastContents), "asset.txt",
+		)
+		require.NoError(t, err)
+	}
+	rel, _, err = clt.GetRelease(test.Username, test.EmptyRepo, rel.ID)
+	require.NoError(t, err)
+	require.Len(t, rel.Attachments, 5)
+
+	// The repository is public, so the assets can be downloaded without authentication.
+	destDir := t.TempDir()
+	require.NoError(t, DownloadReleaseAssets(clt, gohttp.DefaultClient, rel, destDir, nil, 5))
+
+	contents, err := os.ReadFile(filepath.Join(destDir, "asset.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, lastContents, string(contents))
+}
+
+func TestSortReleases(t *testing.T) {
+	t.Parallel()
+
+	baseTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// Releases in the order returned by Gitea, where v0.1.1 was published as a backport after v0.2.0.
+	newReleases := func() []*gitea.Release {
+		return []*gitea.Release{
+			{ID: 5, TagName: "nightly", CreatedAt: baseTime.Add(3 * time.Hour), PublishedAt: baseTime.Add(5 * time.Hour)},
+			{ID: 4, TagName: "v0.1.1", CreatedAt: baseTime.Add(4 * time.Hour), PublishedAt: baseTime.Add(4 * time.Hour)},
+			{ID: 3, TagName: "v0.2.0", CreatedAt: baseTime.Add(2 * time.Hour), PublishedAt: baseTime.Add(3 * time.Hour)},
+			{ID: 2, TagName: "v0.2.0-alpha.1", CreatedAt: baseTime.Add(1 * time.Hour), PublishedAt: baseTime.Add(2 * time.Hour)},
+			{ID: 1, TagName: "v0.1.0", CreatedAt: baseTime, PublishedAt: baseTime},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		order        ReleaseOrder
+		expectedTags []string
+	}{
+		{"Gitea", OrderByGitea, []string{"nightly", "v0.1.1", "v0.2.0", "v0.2.0-alpha.1", "v0.1.0"}},
+		{"Semver", OrderBySemver, []string{"v0.2.0", "v0.2.0-alpha.1", "v0.1.1", "v0.1.0", "nightly"}},
+		{"PublishedAt", OrderByPublishedAt, []string{"nightly", "v0.1.1", "v0.2.0", "v0.2.0-alpha.1", "v0.1.0"}},
+		{"CreatedAt", OrderByCreatedAt, []string{"v0.1.1", "nightly", "v0.2.0", "v0.2.0-alpha.1", "v0.1.0"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			releases := newReleases()
+			SortReleases(releases, ListReleaseOpts{OrderBy: tc.order})
+
+			tags := []string{}
+			for _, rel := range releases {
+				tags = append(tags, rel.TagName)
+			}
+			assert.Equal(t, tc.expectedTags, tags)
+		})
+	}
+}
+
+func TestReleasesSortedBefore(t *testing.T) {
+	t.Parallel()
+
+	baseTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// Releases with a mix of semver and non-semver tags, where v0.2.0 and nightly were published at the same time.
+	newReleases := func() []*gitea.Release {
+		return []*gitea.Release{
+			{ID: 1, TagName: "v0.1.0", PublishedAt: baseTime},
+			{ID: 4, TagName: "nightly", PublishedAt: baseTime.Add(2 * time.Hour)},
+			{ID: 5, TagName: "v0.1.1", PublishedAt: baseTime.Add(3 * time.Hour)},
+			{ID: 2, TagName: "v0.2.0", PublishedAt: baseTime.Add(2 * time.Hour)},
+			{ID: 3, TagName: "latest", PublishedAt: baseTime.Add(1 * time.Hour)},
+		}
+	}
+
+	opts := ListReleaseOpts{OrderBy: OrderByGitea}
+	releases := newReleases()
+	SortReleases(releases, opts)
+
+	tags := []string{}
+	for _, rel := range releases {
+		tags = append(tags, rel.TagName)
+	}
+	require.Equal(t, []string{"v0.1.1", "nightly", "v0.2.0", "latest", "v0.1.0"}, tags)
+
+	// The releases before each release must be exactly the ones that SortReleases put before it.
+	for i, rel := range releases {
+		assert.Equal(t, releases[:i], ReleasesSortedBefore(releases, rel, opts), rel.TagName)
+	}
+}
+
+func TestNewerReleases(t *testing.T) {
+	t.Parallel()
+
+	baseTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	// v1.0.5 is a backport that was published after v2.0.0.
+	releases := []*gitea.Release{
+		{ID: 4, TagName: "nightly", PublishedAt: baseTime.Add(3 * time.Hour)},
+		{ID: 3, TagName: "v1.0.5", PublishedAt: baseTime.Add(2 * time.Hour)},
+		{ID: 2, TagName: "v2.0.0", PublishedAt: baseTime.Add(1 * time.Hour)},
+		{ID: 1, TagName: "v1.0.4", PublishedAt: baseTime},
+	}
+
+	testCases := []struct {
+		name         string
+		order        ReleaseOrder
+		currentTag   string
+		expectedTags []string
+	}{
+		{"GiteaSkipsBackport", OrderByGitea, "v2.0.0", []string{"nightly"}},
+		{"GiteaIncludesBackport", OrderByGitea, "v1.0.4", []string{"nightly", "v1.0.5", "v2.0.0"}},
+		{"PublishedAtIncludesBackport", OrderByPublishedAt, "v2.0.0", []string{"nightly", "v1.0.5"}},
+		{"Semver", OrderBySemver, "v1.0.5", []string{"v2.0.0"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var current *gitea.Release
+			for _, rel := range releases {
+				if rel.TagName == tc.currentTag {
+					current = rel
+				}
+			}
+			require.NotNil(t, current)
+
+			tags := []string{}
+			for _, rel := range NewerReleases(releases, current, ListReleaseOpts{OrderBy: tc.order}) {
+				tags = append(tags, rel.TagName)
+			}
+			assert.Equal(t, tc.expectedTags, tags)
+		})
+	}
+}
+
+func TestNewReleaseOrder(t *testing.T) {
+	t.Parallel()
+
+	order, err := NewReleaseOrder("")
+	require.NoError(t, err)
+	assert.Equal(t, OrderByGitea, order)
+
+	order, err = NewReleaseOrder("semver")
+	require.NoError(t, err)
+	assert.Equal(t, OrderBySemver, order)
+
+	_, err = NewReleaseOrder("alphabetical")
+	assert.Error(t, err)
+}
+
+func TestNewCheckMode(t *testing.T) {
+	t.Parallel()
+
+	mode, err := NewCheckMode("")
+	require.NoError(t, err)
+	assert.Equal(t, CheckMode(""), mode)
+
+	mode, err = NewCheckMode("every")
+	require.NoError(t, err)
+	assert.Equal(t, CheckEvery, mode)
+
+	_, err = NewCheckMode("all")
+	assert.Error(t, err)
+}
+
+func TestNewMissingVersionPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, err := NewMissingVersionPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, MissingVersionLatest, policy)
+
+	policy, err = NewMissingVersionPolicy("empty")
+	require.NoError(t, err)
+	assert.Equal(t, MissingVersionEmpty, policy)
+
+	_, err = NewMissingVersionPolicy("ignore")
+	assert.Error(t, err)
+}
+
+func TestReleaseVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		tag             string
+		tagFilter       string
+		expectedVersion string
+	}{
+		{"SemverWithoutFilter", "v1.2.3", "", "1.2.3"},
+		{"NonSemverWithoutFilter", "release-2024.05.1", "", ""},
+		{"FilterWithoutCaptureGroup", "v1.2.3", "^v", "1.2.3"},
+		{"FilterWithCaptureGroup", "release-2024.05.1", "^release-(.+)$", "2024.5.1"},
+		{"FilterWithNonSemverCapture", "build-abc", "^build-(.+)$", ""},
+		{"TagNotMatchingFilter", "build-1234", "^release-(.+)$", ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var tagFilter *regexp.Regexp
+			if tc.tagFilter != "" {
+				tagFilter = regexp.MustCompile(tc.tagFilter)
+			}
+
+			v, err := ReleaseVersion(tc.tag, tagFilter)
+			if tc.expectedVersion == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedVersion, v.String())
+		})
+	}
+}
@@ -1,35 +1,186 @@
 package http
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 )
 
-// DownloadFileOverHTTP will retrieve the given URL over HTTP and download the contents to the given destination path.
-func DownloadFileOverHTTP(url, destPath string) error {
-	out, err := os.Create(destPath)
+// ClientOpts is a struct representing the settings for constructing an HTTP client to talk to a Gitea server.
+type ClientOpts struct {
+	// ServerURL is the URL of the Gitea server. Requests to this host are authenticated using AccessToken.
+	ServerURL string
+	// AccessToken is the Gitea API access token to authenticate requests with. Only sent to the Gitea server.
+	AccessToken string
+	// CACert is a set of PEM encoded CA certificates to trust in addition to the system certificate pool.
+	CACert string
+	// InsecureSkipVerify indicates whether TLS certificate verification should be disabled entirely.
+	InsecureSkipVerify bool
+	// MaxRetries is the number of times idempotent requests that fail with a transient error are retried. Set to 0 to
+	// disable retries.
+	MaxRetries int
+}
+
+// NewClient returns an HTTP client configured with the given TLS settings. When an access token is provided, the client
+// authenticates all requests to the Gitea server that don't already have credentials, so that it can be used to
+// download assets from private repositories. Idempotent requests that fail with a transient error are retried up to
+// MaxRetries times.
+func NewClient(opts ClientOpts) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if ok := pool.AppendCertsFromPEM([]byte(opts.CACert)); !ok {
+			return nil, errors.New("no valid PEM encoded certificates found in CA cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	var roundTripper http.RoundTripper = transport
+	if opts.AccessToken != "" {
+		serverURL, err := url.Parse(opts.ServerURL)
+		if err != nil {
+			return nil, err
+		}
+		roundTripper = &tokenTransport{
+			host:  serverURL.Host,
+			token: opts.AccessToken,
+			base:  roundTripper,
+		}
+	}
+	if opts.MaxRetries > 0 {
+		roundTripper = newRetryTransport(opts.MaxRetries, roundTripper)
+	}
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+// tokenTransport is an http.RoundTripper that adds the Gitea access token to requests made to the Gitea server host.
+// Requests to other hosts (e.g., redirects to an object storage backend) are sent without the token.
+type tokenTransport struct {
+	host  string
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the request, so add the header on a copy.
+	authedReq := req.Clone(req.Context())
+	authedReq.Header.Set("Authorization", "token "+t.token)
+	return t.base.RoundTrip(authedReq)
+}
+
+// DownloadFileOverHTTP will retrieve the given URL over HTTP using the provided client and download the contents to the
+// given destination path. The number of bytes downloaded is verified against the Content-Length of the response, as
+// well as expectedSize when it is not negative, so that a truncated download or an unexpected response (like an HTML
+// login page) is not silently written out. The destination file is removed if the download fails.
+//
+// When the client retries requests (see ClientOpts.MaxRetries), the download is also restarted if reading the response
+// body fails with a transient error, like a connection reset when the server restarts mid-download. The client only
+// retries until the response headers are received, so these failures are not covered by it.
+func DownloadFileOverHTTP(clt *http.Client, url, destPath string, expectedSize int64) (returnErr error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
 
-	resp, err := http.Get(url)
+	out, err := os.Create(destPath)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if closeErr := out.Close(); returnErr == nil {
+			returnErr = closeErr
+		}
+		if returnErr != nil {
+			os.Remove(destPath)
+		}
+	}()
+
+	retry, _ := clt.Transport.(*retryTransport)
+	for attempt := 0; ; attempt++ {
+		readErr, err := downloadToFile(clt, req, out, expectedSize)
+		if readErr == nil || retry == nil || attempt >= retry.maxRetries || !isTransientError(readErr) {
+			return err
+		}
+		if err := retry.sleep(req, backoff(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// downloadToFile downloads the response of the given request to the start of out, replacing its contents. Along with
+// the download error, the error from reading the response body is returned separately, so that it can be told apart
+// from errors writing the file.
+func downloadToFile(clt *http.Client, req *http.Request, out *os.File, expectedSize int64) (readErr, err error) {
+	fname := filepath.Base(out.Name())
+
+	if err := out.Truncate(0); err != nil {
+		return nil, err
+	}
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	resp, err := clt.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file `%s`: HTTP status %d", filepath.Base(destPath), resp.StatusCode)
+		return nil, fmt.Errorf("failed to download file `%s`: HTTP status %d", fname, resp.StatusCode)
 	}
 
-	_, err = io.Copy(out, resp.Body)
+	body := &bodyReader{r: resp.Body}
+	written, err := io.Copy(out, body)
 	if err != nil {
-		return err
+		return body.err, err
 	}
 
-	return nil
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return nil, fmt.Errorf(
+			"failed to download file `%s`: truncated download (got %d bytes, Content-Length %d)",
+			fname, written, resp.ContentLength,
+		)
+	}
+	if expectedSize >= 0 && written != expectedSize {
+		return nil, fmt.Errorf(
+			"failed to download file `%s`: unexpected size (got %d bytes, expected %d)",
+			fname, written, expectedSize,
+		)
+	}
+
+	return nil, nil
+}
+
+// bodyReader is an io.Reader that records the error from reading the wrapped response body.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
 }
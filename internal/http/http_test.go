@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientTLS(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	t.Cleanup(srv.Close)
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	testCases := []struct {
+		name               string
+		caCert             string
+		insecureSkipVerify bool
+		expectSuccess      bool
+	}{
+		{"DefaultRejectsSelfSigned", "", false, false},
+		{"CACertTrustsSelfSigned", caCert, false, true},
+		{"InsecureSkipVerify", "", true, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clt, err := NewClient(ClientOpts{CACert: tc.caCert, InsecureSkipVerify: tc.insecureSkipVerify})
+			require.NoError(t, err)
+
+			destPath := filepath.Join(t.TempDir(), "out")
+			err = DownloadFileOverHTTP(clt, srv.URL, destPath, int64(len("hello world")))
+			if !tc.expectSuccess {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, destPath)
+			contents, err := os.ReadFile(destPath)
+			require.NoError(t, err)
+			assert.Equal(t, "hello world", string(contents))
+		})
+	}
+}
+
+func TestNewClientInvalidCACert(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewClient(ClientOpts{CACert: "not a certificate"})
+	assert.Error(t, err)
+}
+
+func TestNewClientOnlyAuthenticatesGiteaServer(t *testing.T) {
+	t.Parallel()
+
+	var storageAuthHeader string
+	storageSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storageAuthHeader = r.Header.Get("Authorization")
+		w.Write([]byte("hello world"))
+	}))
+	defer storageSrv.Close()
+
+	var giteaAuthHeader string
+	giteaSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		giteaAuthHeader = r.Header.Get("Authorization")
+		http.Redirect(w, r, storageSrv.URL, http.StatusFound)
+	}))
+	defer giteaSrv.Close()
+
+	clt, err := NewClient(ClientOpts{ServerURL: giteaSrv.URL, AccessToken: "secret"})
+	require.NoError(t, err)
+
+	destPath := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, DownloadFileOverHTTP(clt, giteaSrv.URL, destPath, -1))
+	assert.Equal(t, "token secret", giteaAuthHeader)
+	assert.Equal(t, "", storageAuthHeader)
+}
+
+func TestDownloadFileOverHTTPRejectsUnexpectedSize(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Sign In</body></html>"))
+	}))
+	defer srv.Close()
+
+	destPath := filepath.Join(t.TempDir(), "out")
+	err := DownloadFileOverHTTP(srv.Client(), srv.URL, destPath, 11)
+	assert.ErrorContains(t, err, "unexpected size")
+	assert.NoFileExists(t, destPath)
+}
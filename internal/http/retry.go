@@ -0,0 +1,193 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a failed request is retried when no limit is configured.
+	DefaultMaxRetries = 3
+
+	// baseBackoff is the delay before the first retry, which is doubled on every subsequent retry.
+	baseBackoff = 500 * time.Millisecond
+	// maxBackoff caps the delay between retries, including delays requested by the server with Retry-After.
+	maxBackoff = 30 * time.Second
+)
+
+// retryTransport is an http.RoundTripper that retries idempotent requests (GET, HEAD, and DELETE) that fail with a
+// transient error, backing off exponentially with jitter between attempts. A request is considered to have failed
+// transiently if it could not be sent, or if the server responded with 429 Too Many Requests, 502 Bad Gateway, 503
+// Service Unavailable, or 504 Gateway Timeout. The Retry-After header is honored on 429 and 503 responses.
+//
+// A failed DELETE may still have been processed by the server (e.g., when a proxy times out waiting for Gitea), in which
+// case the retry fails with 404 Not Found. Since the resource is gone either way, a 404 on a retried DELETE is reported
+// as 204 No Content.
+type retryTransport struct {
+	maxRetries int
+	base       http.RoundTripper
+
+	// sleep waits for the given duration, returning early with the context error if the request is canceled. Overridden
+	// in tests to avoid waiting.
+	sleep func(req *http.Request, d time.Duration) error
+}
+
+func newRetryTransport(maxRetries int, base http.RoundTripper) *retryTransport {
+	return &retryTransport{
+		maxRetries: maxRetries,
+		base:       base,
+		sleep:      sleepContext,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isIdempotent(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt > 0 && req.Method == http.MethodDelete && err == nil && resp.StatusCode == http.StatusNotFound {
+			return alreadyDeleted(resp), nil
+		}
+		if attempt >= t.maxRetries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp); ok {
+				delay = retryAfter
+			}
+			// Drain and close the body of the failed response so that the connection can be reused for the retry.
+			drainBody(resp)
+		}
+		if err := t.sleep(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isIdempotent returns whether the request can be safely sent multiple times. Requests with a body that can't be
+// rewound are never retried.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return isTransientError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTransientError returns whether the error from sending a request may go away on retry. Canceled requests and TLS
+// certificate verification failures will fail the same way every time, so they are not retried.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return false
+	}
+	return true
+}
+
+// backoff returns the delay before the given retry attempt (starting from 0). The delay grows exponentially, with a
+// random jitter of up to half the delay so that concurrent clients don't retry in lockstep.
+func backoff(attempt int) time.Duration {
+	delay := maxBackoff
+	if attempt < 16 {
+		delay = baseBackoff << attempt
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter returns the delay requested by the server in the Retry-After header of a 429 or 503 response, capped
+// at maxBackoff. The header can either be the number of seconds to wait or an HTTP date.
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		delay = time.Until(date)
+	} else {
+		return 0, false
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay, true
+}
+
+// alreadyDeleted replaces the 404 response of a retried DELETE with a successful response, since the 404 means that an
+// earlier attempt deleted the resource.
+func alreadyDeleted(resp *http.Response) *http.Response {
+	drainBody(resp)
+	return &http.Response{
+		Status:     "204 No Content",
+		StatusCode: http.StatusNoContent,
+		Proto:      resp.Proto,
+		ProtoMajor: resp.ProtoMajor,
+		ProtoMinor: resp.ProtoMinor,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    resp.Request,
+	}
+}
+
+func drainBody(resp *http.Response) {
+	// Limit how much is read so that a large error page doesn't hold up the retry.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+}
+
+func sleepContext(req *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
@@ -0,0 +1,222 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTransport(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		method           string
+		failures         int
+		failureStatus    int
+		retryAfter       string
+		maxRetries       int
+		expectedStatus   int
+		expectedAttempts int32
+		expectedDelays   []time.Duration
+	}{
+		{"RetriesBadGateway", http.MethodGet, 2, http.StatusBadGateway, "", 3, http.StatusOK, 3, nil},
+		{"RetriesDelete", http.MethodDelete, 1, http.StatusServiceUnavailable, "", 3, http.StatusOK, 2, nil},
+		{"GivesUpAfterMaxRetries", http.MethodGet, 5, http.StatusGatewayTimeout, "", 2, http.StatusGatewayTimeout, 3, nil},
+		{"DoesNotRetryPost", http.MethodPost, 1, http.StatusBadGateway, "", 3, http.StatusBadGateway, 1, nil},
+		{"DoesNotRetryNotFound", http.MethodGet, 1, http.StatusNotFound, "", 3, http.StatusNotFound, 1, nil},
+		{
+			"HonorsRetryAfter",
+			http.MethodGet, 2, http.StatusTooManyRequests, "7", 3, http.StatusOK, 3,
+			[]time.Duration{7 * time.Second, 7 * time.Second},
+		},
+		{
+			"CapsRetryAfter",
+			http.MethodGet, 1, http.StatusTooManyRequests, "3600", 3, http.StatusOK, 2,
+			[]time.Duration{maxBackoff},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var attempts int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&attempts, 1) <= int32(tc.failures) {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.failureStatus)
+					w.Write([]byte("try again later"))
+					return
+				}
+				w.Write([]byte("hello world"))
+			}))
+			t.Cleanup(srv.Close)
+
+			var delays []time.Duration
+			transport := newRetryTransport(tc.maxRetries, http.DefaultTransport)
+			transport.sleep = func(_ *http.Request, d time.Duration) error {
+				delays = append(delays, d)
+				return nil
+			}
+			clt := &http.Client{Transport: transport}
+
+			req, err := http.NewRequest(tc.method, srv.URL, nil)
+			require.NoError(t, err)
+			resp, err := clt.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tc.expectedAttempts, atomic.LoadInt32(&attempts))
+			if tc.expectedDelays != nil {
+				assert.Equal(t, tc.expectedDelays, delays)
+			}
+		})
+	}
+}
+
+func TestRetryTransportDeleteAlreadyDeleted(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		existing         bool
+		expectedStatus   int
+		expectedAttempts int32
+	}{
+		// The first attempt deletes the asset but times out at the proxy, so the retry finds nothing to delete.
+		{"DeletedByFirstAttempt", true, http.StatusNoContent, 2},
+		// A 404 on the first attempt is not retried, so it is still reported as missing.
+		{"NeverExisted", false, http.StatusNotFound, 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var attempts int32
+			var mu sync.Mutex
+			existing := tc.existing
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&attempts, 1)
+				mu.Lock()
+				defer mu.Unlock()
+				if !existing {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				existing = false
+				w.WriteHeader(http.StatusGatewayTimeout)
+			}))
+			t.Cleanup(srv.Close)
+
+			transport := newRetryTransport(3, http.DefaultTransport)
+			transport.sleep = func(*http.Request, time.Duration) error { return nil }
+			clt := &http.Client{Transport: transport}
+
+			req, err := http.NewRequest(http.MethodDelete, srv.URL, nil)
+			require.NoError(t, err)
+			resp, err := clt.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tc.expectedAttempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func TestDownloadFileOverHTTPRetriesBodyErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		failures         int
+		maxRetries       int
+		expectSuccess    bool
+		expectedAttempts int32
+	}{
+		{"RecoversFromReset", 2, 3, true, 3},
+		{"GivesUpAfterMaxRetries", 5, 2, false, 3},
+		{"NoRetries", 1, 0, false, 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var attempts int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "11")
+				if atomic.AddInt32(&attempts, 1) > int32(tc.failures) {
+					w.Write([]byte("hello world"))
+					return
+				}
+
+				// Send part of the body, then drop the connection like a server that restarts mid-download.
+				w.Write([]byte("hello"))
+				w.(http.Flusher).Flush()
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err == nil {
+					conn.Close()
+				}
+			}))
+			t.Cleanup(srv.Close)
+
+			clt := &http.Client{}
+			if tc.maxRetries > 0 {
+				transport := newRetryTransport(tc.maxRetries, http.DefaultTransport)
+				transport.sleep = func(*http.Request, time.Duration) error { return nil }
+				clt.Transport = transport
+			}
+
+			destPath := filepath.Join(t.TempDir(), "out")
+			err := DownloadFileOverHTTP(clt, srv.URL, destPath, 11)
+			assert.Equal(t, tc.expectedAttempts, atomic.LoadInt32(&attempts))
+			if !tc.expectSuccess {
+				assert.Error(t, err)
+				assert.NoFileExists(t, destPath)
+				return
+			}
+			require.NoError(t, err)
+			contents, err := os.ReadFile(destPath)
+			require.NoError(t, err)
+			assert.Equal(t, "hello world", string(contents))
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	for attempt, expectedMax := range []time.Duration{
+		500 * time.Millisecond,
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+	} {
+		delay := backoff(attempt)
+		assert.GreaterOrEqual(t, delay, expectedMax/2)
+		assert.LessOrEqual(t, delay, expectedMax)
+	}
+
+	assert.LessOrEqual(t, backoff(100), maxBackoff)
+	assert.GreaterOrEqual(t, backoff(100), maxBackoff/2)
+}
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"os"
 	"strconv"
 	"time"
 
@@ -83,6 +84,32 @@ func MetadataFromRelease(release *gitea.Release) []MetadataPair {
 			Value: "true",
 		})
 	}
+
+	if release.IsDraft {
+		metadata = append(metadata, MetadataPair{
+			Name:  "draft",
+			Value: "true",
+		})
+	}
+
+	if release.Publisher != nil && release.Publisher.UserName != "" {
+		metadata = append(metadata, MetadataPair{
+			Name:  "author",
+			Value: release.Publisher.UserName,
+		})
+	}
+
+	if !release.PublishedAt.IsZero() {
+		metadata = append(metadata, MetadataPair{
+			Name:  "published_at",
+			Value: release.PublishedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	metadata = append(metadata, MetadataPair{
+		Name:  "assets",
+		Value: strconv.Itoa(len(release.Attachments)),
+	})
 	return metadata
 }
 
@@ -93,9 +120,32 @@ type Source struct {
 	Repository string `json:"repository"`
 
 	// Optional
-	AccessToken      string `json:"access_token"`
-	SemverConstraint string `json:"semver_constraint"`
-	PreRelease       bool   `json:"pre_release"`
+	AccessToken        string   `json:"access_token"`
+	SemverConstraint   string   `json:"semver_constraint"`
+	PreRelease         bool     `json:"pre_release"`
+	CACert             string   `json:"ca_cert"`
+	InsecureSkipVerify bool     `json:"insecure_skip_verify"`
+	OrderBy            string   `json:"order_by"`
+	TagFilter          string   `json:"tag_filter"`
+	TargetCommitish    string   `json:"target_commitish"`
+	TitleRegex         string   `json:"title_regex"`
+	BodyRegex          string   `json:"body_regex"`
+	RequireAssets      []string `json:"require_assets"`
+	ReleaseAuthor      []string `json:"release_author"`
+	Drafts             string   `json:"drafts"`
+	MaxRetries         *int     `json:"max_retries"`
+	CheckMode          string   `json:"check_mode"`
+	OnMissingVersion   string   `json:"on_missing_version"`
+
+	VerifySignature      *SignatureConfig `json:"verify_signature"`
+	SigningKey           string           `json:"signing_key"`
+	SigningKeyPassphrase string           `json:"signing_key_passphrase"`
+}
+
+// SignatureConfig holds the public keys that are trusted for verifying the signatures of release assets.
+type SignatureConfig struct {
+	GPGPublicKeys      []string `json:"gpg_public_keys"`
+	MinisignPublicKeys []string `json:"minisign_public_keys"`
 }
 
 type CheckRequest struct {
@@ -110,7 +160,13 @@ type InRequest struct {
 }
 
 type InParams struct {
-	Globs []string `json:"globs"`
+	Globs                []string `json:"globs"`
+	Parallelism          int      `json:"parallelism"`
+	VerifyChecksums      bool     `json:"verify_checksums"`
+	Unpack               bool     `json:"unpack"`
+	IncludeSourceZip     bool     `json:"include_source_zip"`
+	IncludeSourceTarball bool     `json:"include_source_tarball"`
+	SkipDownload         bool     `json:"skip_download"`
 }
 
 type OutRequest struct {
@@ -125,7 +181,46 @@ type OutParams struct {
 	TargetPath string `json:"target_path"`
 	IDPath     string `json:"id_path"`
 
-	Globs []string `json:"globs"`
+	Globs         []string `json:"globs"`
+	AssetConflict string   `json:"asset_conflict"`
+	PruneAssets   bool     `json:"prune_assets"`
+	Parallelism   int      `json:"parallelism"`
+
+	GenerateChecksums string `json:"generate_checksums"`
+
+	GenerateNotes     bool   `json:"generate_notes"`
+	NotesTemplatePath string `json:"notes_template_path"`
+	Template          bool   `json:"template"`
+
+	Bump string `json:"bump"`
+
+	Draft   bool `json:"draft"`
+	Publish bool `json:"publish"`
+}
+
+// BuildMetadata is the metadata of the Concourse build that is running the resource, as provided through the
+// environment. Refer to https://concourse-ci.org/implementing-resource-types.html#resource-metadata for details.
+type BuildMetadata struct {
+	ID                   string
+	Name                 string
+	JobName              string
+	PipelineName         string
+	PipelineInstanceVars string
+	TeamName             string
+	ATCExternalURL       string
+}
+
+// BuildMetadataFromEnv returns the BuildMetadata from the environment variables that Concourse sets for put steps.
+func BuildMetadataFromEnv() BuildMetadata {
+	return BuildMetadata{
+		ID:                   os.Getenv("BUILD_ID"),
+		Name:                 os.Getenv("BUILD_NAME"),
+		JobName:              os.Getenv("BUILD_JOB_NAME"),
+		PipelineName:         os.Getenv("BUILD_PIPELINE_NAME"),
+		PipelineInstanceVars: os.Getenv("BUILD_PIPELINE_INSTANCE_VARS"),
+		TeamName:             os.Getenv("BUILD_TEAM_NAME"),
+		ATCExternalURL:       os.Getenv("ATC_EXTERNAL_URL"),
+	}
 }
 
 type InOutResponse struct {
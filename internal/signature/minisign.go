@@ -0,0 +1,115 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// The minisign formats are documented at https://jedisct1.github.io/minisign/.
+const (
+	minisignUntrustedCommentPrefix = "untrusted comment:"
+	minisignTrustedCommentPrefix   = "trusted comment: "
+
+	// minisignPureAlgorithm signs the file contents directly, while minisignHashedAlgorithm signs the BLAKE2b-512 hash
+	// of the contents.
+	minisignPureAlgorithm   = "Ed"
+	minisignHashedAlgorithm = "ED"
+
+	minisignKeyIDLen = 8
+)
+
+type minisignPublicKey struct {
+	keyID [minisignKeyIDLen]byte
+	key   ed25519.PublicKey
+}
+
+// parseMinisignPublicKey parses either the base64 encoded public key, or the contents of a minisign public key file
+// with the untrusted comment line.
+func parseMinisignPublicKey(keyStr string) (*minisignPublicKey, error) {
+	var encoded string
+	for _, line := range strings.Split(strings.TrimSpace(keyStr), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, minisignUntrustedCommentPrefix) {
+			encoded = line
+		}
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != 2+minisignKeyIDLen+ed25519.PublicKeySize || string(decoded[:2]) != minisignPureAlgorithm {
+		return nil, errors.New("not a minisign Ed25519 public key")
+	}
+
+	out := &minisignPublicKey{key: ed25519.PublicKey(decoded[2+minisignKeyIDLen:])}
+	copy(out.keyID[:], decoded[2:2+minisignKeyIDLen])
+	return out, nil
+}
+
+// verifyMinisign checks the minisign signature of the given data, including the signature of the trusted comment.
+func verifyMinisign(keys []*minisignPublicKey, data, sigFile []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(sigFile)), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], minisignTrustedCommentPrefix) {
+		return errors.New("malformed minisign signature")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return fmt.Errorf("malformed minisign signature: %w", err)
+	}
+	if len(sig) != 2+minisignKeyIDLen+ed25519.SignatureSize {
+		return errors.New("malformed minisign signature: unexpected length")
+	}
+	algorithm, keyID, edSig := string(sig[:2]), sig[2:2+minisignKeyIDLen], sig[2+minisignKeyIDLen:]
+
+	trustedComment := strings.TrimSuffix(strings.TrimPrefix(lines[2], minisignTrustedCommentPrefix), "\r")
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return fmt.Errorf("malformed minisign signature: %w", err)
+	}
+
+	var key *minisignPublicKey
+	for _, k := range keys {
+		if bytes.Equal(k.keyID[:], keyID) {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("minisign signature is signed by untrusted key %X", reverse(keyID))
+	}
+
+	message := data
+	switch algorithm {
+	case minisignPureAlgorithm:
+	case minisignHashedAlgorithm:
+		hashed := blake2b.Sum512(data)
+		message = hashed[:]
+	default:
+		return fmt.Errorf("unsupported minisign signature algorithm %q", algorithm)
+	}
+
+	if !ed25519.Verify(key.key, message, edSig) {
+		return errors.New("invalid minisign signature")
+	}
+	if !ed25519.Verify(key.key, append(append([]byte{}, edSig...), trustedComment...), globalSig) {
+		return errors.New("invalid minisign trusted comment signature")
+	}
+	return nil
+}
+
+// reverse returns a reversed copy of the given bytes. Minisign displays key IDs as little endian numbers.
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
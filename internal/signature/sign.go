@@ -0,0 +1,73 @@
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// SignedExtension is the extension of the detached signatures produced by Signer.
+const SignedExtension = ".asc"
+
+// Signer produces ASCII armored detached GPG signatures.
+type Signer struct {
+	entity *openpgp.Entity
+}
+
+// NewSigner returns a Signer that signs with the given ASCII armored GPG private key. The passphrase is used to decrypt
+// the key if it is encrypted.
+func NewSigner(armoredPrivateKey, passphrase string) (*Signer, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armoredPrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GPG private key: %w", err)
+	}
+
+	var entity *openpgp.Entity
+	for _, e := range entities {
+		if e.PrivateKey != nil {
+			entity = e
+			break
+		}
+	}
+	if entity == nil {
+		return nil, errors.New("no GPG private key found")
+	}
+
+	if entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("error decrypting GPG private key: %w", err)
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err := subkey.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("error decrypting GPG private subkey: %w", err)
+			}
+		}
+	}
+	return &Signer{entity: entity}, nil
+}
+
+// SignFile writes the detached signature of the file at the given path to destPath.
+func (s *Signer) SignFile(path, destPath string) (returnErr error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := out.Close(); returnErr == nil {
+			returnErr = closeErr
+		}
+	}()
+
+	return openpgp.ArmoredDetachSign(out, s.entity, f, nil)
+}
@@ -0,0 +1,94 @@
+// Package signature contains routines for verifying detached GPG and minisign signatures of release assets.
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Extensions lists the file extensions of detached signature assets, in the order they are looked up for an asset.
+var Extensions = []string{".sig", ".asc", ".minisig"}
+
+// IsSignature returns whether the given asset name is a detached signature file, based on the extension.
+func IsSignature(name string) bool {
+	for _, ext := range Extensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier verifies detached signatures against a set of trusted GPG and minisign public keys.
+type Verifier struct {
+	gpgKeyRing   openpgp.EntityList
+	minisignKeys []*minisignPublicKey
+}
+
+// NewVerifier returns a Verifier that trusts the given ASCII armored GPG public keys and minisign public keys. The
+// minisign keys can either be the base64 encoded key, or the contents of the public key file generated by minisign.
+func NewVerifier(gpgPublicKeys, minisignPublicKeys []string) (*Verifier, error) {
+	if len(gpgPublicKeys) == 0 && len(minisignPublicKeys) == 0 {
+		return nil, errors.New("at least one trusted GPG or minisign public key is required")
+	}
+
+	v := &Verifier{}
+	for i, key := range gpgPublicKeys {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing GPG public key %d: %w", i, err)
+		}
+		v.gpgKeyRing = append(v.gpgKeyRing, entities...)
+	}
+	for i, key := range minisignPublicKeys {
+		parsed, err := parseMinisignPublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing minisign public key %d: %w", i, err)
+		}
+		v.minisignKeys = append(v.minisignKeys, parsed)
+	}
+	return v, nil
+}
+
+// VerifyFile checks that the given detached signature is a valid signature of the file at the given path by one of the
+// trusted keys. The signature format (minisign, ASCII armored GPG, or binary GPG) is detected from the contents.
+func (v *Verifier) VerifyFile(path string, sig []byte) error {
+	fname := filepath.Base(path)
+	if err := v.verifyFile(path, sig); err != nil {
+		return fmt.Errorf("signature verification failed for %s: %w", fname, err)
+	}
+	return nil
+}
+
+func (v *Verifier) verifyFile(path string, sig []byte) error {
+	if bytes.HasPrefix(sig, []byte(minisignUntrustedCommentPrefix)) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return verifyMinisign(v.minisignKeys, data, sig)
+	}
+
+	if len(v.gpgKeyRing) == 0 {
+		return errors.New("GPG signature found, but no trusted GPG public keys are configured")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN PGP SIGNATURE-----")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(v.gpgKeyRing, f, bytes.NewReader(sig), nil)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(v.gpgKeyRing, f, bytes.NewReader(sig), nil)
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/yorinasub17/concourse-gitea-release-resource/test"
+)
+
+func TestVerifyFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "asset.tar.gz")
+	data := []byte("release asset contents")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	gpgKey, err := test.NewTestGPGKey()
+	require.NoError(t, err)
+	otherGPGKey, err := test.NewTestGPGKey()
+	require.NoError(t, err)
+	minisignKey, minisignPublicKey := newTestMinisignKey(t)
+	otherMinisignKey, _ := newTestMinisignKey(t)
+
+	var armoredSig, binarySig, otherGPGSig bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&armoredSig, gpgKey.Entity, bytes.NewReader(data), nil))
+	require.NoError(t, openpgp.DetachSign(&binarySig, gpgKey.Entity, bytes.NewReader(data), nil))
+	require.NoError(t, openpgp.ArmoredDetachSign(&otherGPGSig, otherGPGKey.Entity, bytes.NewReader(data), nil))
+
+	tamperedMinisignSig := minisignKey.sign(data, true)
+	tamperedMinisignSig = bytes.Replace(tamperedMinisignSig, []byte("timestamp:0"), []byte("timestamp:1"), 1)
+
+	testCases := []struct {
+		name          string
+		sig           []byte
+		expectSuccess bool
+	}{
+		{"ArmoredGPG", armoredSig.Bytes(), true},
+		{"BinaryGPG", binarySig.Bytes(), true},
+		{"UntrustedGPG", otherGPGSig.Bytes(), false},
+		{"HashedMinisign", minisignKey.sign(data, true), true},
+		{"PureMinisign", minisignKey.sign(data, false), true},
+		{"UntrustedMinisign", otherMinisignKey.sign(data, true), false},
+		{"WrongDataMinisign", minisignKey.sign([]byte("other contents"), true), false},
+		{"TamperedTrustedCommentMinisign", tamperedMinisignSig, false},
+		{"Garbage", []byte("not a signature"), false},
+	}
+
+	verifier, err := NewVerifier([]string{gpgKey.ArmoredPublicKey}, []string{minisignPublicKey})
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := verifier.VerifyFile(path, tc.sig)
+			if tc.expectSuccess {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, "asset.tar.gz")
+			}
+		})
+	}
+}
+
+func TestNewVerifierRequiresKeys(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewVerifier(nil, nil)
+	assert.Error(t, err)
+
+	_, err = NewVerifier(nil, []string{"not a key"})
+	assert.Error(t, err)
+}
+
+func TestSignFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "asset.tar.gz")
+	require.NoError(t, os.WriteFile(path, []byte("release asset contents"), 0o644))
+
+	key, err := test.NewTestGPGKey()
+	require.NoError(t, err)
+
+	signer, err := NewSigner(key.ArmoredPrivateKey, "")
+	require.NoError(t, err)
+	sigPath := path + SignedExtension
+	require.NoError(t, signer.SignFile(path, sigPath))
+
+	sig, err := os.ReadFile(sigPath)
+	require.NoError(t, err)
+	verifier, err := NewVerifier([]string{key.ArmoredPublicKey}, nil)
+	require.NoError(t, err)
+	assert.NoError(t, verifier.VerifyFile(path, sig))
+
+	_, err = NewSigner(key.ArmoredPublicKey, "")
+	assert.Error(t, err)
+}
+
+type testMinisignKey struct {
+	keyID []byte
+	key   ed25519.PrivateKey
+}
+
+func newTestMinisignKey(t *testing.T) (*testMinisignKey, string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyID := make([]byte, minisignKeyIDLen)
+	_, err = rand.Read(keyID)
+	require.NoError(t, err)
+
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte(minisignPureAlgorithm), keyID...), pub...))
+	return &testMinisignKey{keyID: keyID, key: priv}, "untrusted comment: minisign public key\n" + encoded + "\n"
+}
+
+func (k *testMinisignKey) sign(data []byte, hashed bool) []byte {
+	algorithm := minisignPureAlgorithm
+	message := data
+	if hashed {
+		algorithm = minisignHashedAlgorithm
+		sum := blake2b.Sum512(data)
+		message = sum[:]
+	}
+
+	sig := ed25519.Sign(k.key, message)
+	trustedComment := "timestamp:0\tfile:asset.tar.gz"
+	globalSig := ed25519.Sign(k.key, append(append([]byte{}, sig...), trustedComment...))
+	return []byte(fmt.Sprintf(
+		"untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), k.keyID...), sig...)),
+		trustedComment,
+		base64.StdEncoding.EncodeToString(globalSig),
+	))
+}
@@ -13,7 +13,7 @@ sleep 10
 >&2 echo 'Loading test repo and releases to test gitea container'
 go run "$TEST_DIR"/setup
 
-go test -v -count 1 ./...
+TEST_LIVE_GITEA=true go test -v -count 1 ./...
 TEST_EXIT_CODE="$?"
 
 >&2 echo 'Stopping test gitea container'
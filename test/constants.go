@@ -11,4 +11,9 @@ const (
 	PrivateRepo                    = "fooprivate"
 	NoReleasesRepo                 = "noreleases"
 	EmptyRepo                      = "empty"
+	NonSemverRepo                  = "foo-nonsemver"
+
+	// LiveServerEnvVar is the environment variable that switches the tests to run against the live Gitea server at
+	// ServerURL instead of the in-memory fake.
+	LiveServerEnvVar = "TEST_LIVE_GITEA"
 )
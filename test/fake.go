@@ -0,0 +1,107 @@
+package test
+
+import (
+	"os"
+	"strings"
+
+	"code.gitea.io/sdk/gitea"
+	"github.com/gruntwork-io/go-commons/random"
+
+	"github.com/yorinasub17/concourse-gitea-release-resource/test/fakegitea"
+)
+
+// UseLiveServer returns whether the tests should run against the live Gitea server at ServerURL (as started by
+// scripts/run_test.sh), instead of the in-memory fake.
+func UseLiveServer() bool {
+	return os.Getenv(LiveServerEnvVar) != ""
+}
+
+// NewFakeServer starts an in-memory fake Gitea server loaded with the same test repositories and releases that the
+// test/setup command loads into a live Gitea server.
+func NewFakeServer() (*fakegitea.Server, error) {
+	return loadFakeTestData(fakegitea.NewServer())
+}
+
+// NewFakeTLSServer is the same as NewFakeServer, except the server is served over HTTPS using a self-signed
+// certificate.
+func NewFakeTLSServer() (*fakegitea.Server, error) {
+	return loadFakeTestData(fakegitea.NewTLSServer())
+}
+
+func loadFakeTestData(srv *fakegitea.Server) (*fakegitea.Server, error) {
+	srv.CreateUser(Username, Password)
+
+	repos := []struct {
+		name          string
+		private       bool
+		releases      []TestRelease
+		includeAssets bool
+	}{
+		{PrivateRepo, true, SemverTestReleases(4), false},
+		{PublicRepo, false, SemverTestReleases(4), true},
+		{PublicRepoWithPrereleaseLatest, false, SemverTestReleases(5), false},
+		{NoReleasesRepo, false, nil, false},
+		{EmptyRepo, false, nil, false},
+		{NonSemverRepo, false, NonSemverTestReleases, false},
+	}
+	for _, repo := range repos {
+		if _, err := srv.CreateRepo(Username, repo.name, repo.private); err != nil {
+			srv.Close()
+			return nil, err
+		}
+		if err := loadFakeTestReleases(srv, repo.name, repo.releases, repo.includeAssets); err != nil {
+			srv.Close()
+			return nil, err
+		}
+	}
+	return srv, nil
+}
+
+// loadFakeTestReleases mirrors the releases that test/setup cuts, with a new commit for each release.
+func loadFakeTestReleases(srv *fakegitea.Server, repoName string, releases []TestRelease, includeAssets bool) error {
+	for _, testRelease := range releases {
+		releaseName := testRelease.Tag
+
+		sha, err := srv.CreateCommit(Username, repoName, "random file")
+		if err != nil {
+			return err
+		}
+
+		release, err := srv.CreateRelease(Username, repoName, gitea.CreateReleaseOption{
+			TagName:      releaseName,
+			Target:       sha,
+			Title:        releaseName,
+			Note:         "release " + releaseName,
+			IsPrerelease: testRelease.PreRelease,
+		})
+		if err != nil {
+			return err
+		}
+
+		if includeAssets {
+			asset1Str, err := random.RandomString(6, random.Base62Chars)
+			if err != nil {
+				return err
+			}
+			asset2Str, err := random.RandomString(6, random.Base62Chars)
+			if err != nil {
+				return err
+			}
+
+			assets := []struct {
+				name     string
+				contents string
+			}{
+				{"tag", strings.Join([]string{releaseName, asset1Str, asset2Str}, "\n")},
+				{"asset1", asset1Str},
+				{"asset2", asset2Str},
+			}
+			for _, asset := range assets {
+				if _, err := srv.CreateReleaseAttachment(Username, repoName, release.ID, asset.name, []byte(asset.contents)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
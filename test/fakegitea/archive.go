@@ -0,0 +1,87 @@
+package fakegitea
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// sourceArchiveFile is the single file in the fake source archives, containing the commit SHA that the archive was
+// generated from.
+const sourceArchiveFile = "COMMIT"
+
+// serveSourceArchive serves the source archive of the given ref, like the /{owner}/{repo}/archive/{ref}.{zip,tar.gz}
+// route of Gitea. The fake archive contains a directory named after the repository, with a single file containing the
+// commit SHA of the ref.
+func (s *Server) serveSourceArchive(w http.ResponseWriter, r *http.Request, authUser *user, owner, repoName, fname string) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return errMethodNotAllowed
+	}
+	repo, err := s.lookupRepo(authUser, owner, repoName)
+	if err != nil {
+		return err
+	}
+
+	var ref string
+	var build func(prefix, sha string) ([]byte, error)
+	switch {
+	case strings.HasSuffix(fname, ".zip"):
+		ref, build = strings.TrimSuffix(fname, ".zip"), buildZipSourceArchive
+	case strings.HasSuffix(fname, ".tar.gz"):
+		ref, build = strings.TrimSuffix(fname, ".tar.gz"), buildTarGzSourceArchive
+	default:
+		return notFoundErr("unsupported archive format %s", fname)
+	}
+
+	sha, ok := resolveRef(repo, ref)
+	if !ok {
+		return notFoundErr("ref %s does not exist", ref)
+	}
+	data, err := build(repo.apiRepo.Name, sha)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, fname, s.clock, bytes.NewReader(data))
+	return nil
+}
+
+func buildZipSourceArchive(prefix, sha string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create(prefix + "/" + sourceArchiveFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write([]byte(sha)); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func buildTarGzSourceArchive(prefix, sha string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	hdr := &tar.Header{Name: prefix + "/" + sourceArchiveFile, Mode: 0o644, Size: int64(len(sha))}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write([]byte(sha)); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,3 @@
+// Package fakegitea contains an in-memory fake of the subset of the Gitea API that the resource interacts with, so
+// that tests can run hermetically without a live Gitea server.
+package fakegitea
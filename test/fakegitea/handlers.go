@@ -0,0 +1,694 @@
+package fakegitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+const (
+	// defaultPageSize and maxPageSize mirror the DEFAULT_PAGING_NUM and MAX_RESPONSE_ITEMS defaults of Gitea.
+	defaultPageSize = 30
+	maxPageSize     = 50
+)
+
+// apiError is an error that is returned to the client with the given HTTP status code.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (err apiError) Error() string {
+	return err.message
+}
+
+func newAPIError(status int, format string, args ...interface{}) apiError {
+	return apiError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+func notFoundErr(format string, args ...interface{}) apiError {
+	return newAPIError(http.StatusNotFound, format, args...)
+}
+
+var (
+	errUnauthorized     = newAPIError(http.StatusUnauthorized, "invalid credentials")
+	errForbidden        = newAPIError(http.StatusForbidden, "user does not have write access to the repository")
+	errMethodNotAllowed = newAPIError(http.StatusMethodNotAllowed, "method not allowed")
+	errNotFound         = notFoundErr("not found")
+)
+
+// ServeHTTP implements http.Handler, routing the request to the fake API endpoints. All requests are handled with the
+// server lock held, so requests are processed one at a time.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := pathSegments(r.URL)
+	if err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, "invalid path: %s", err))
+		return
+	}
+
+	authUser, err := s.authenticate(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	switch {
+	case len(segments) >= 2 && segments[0] == "api" && segments[1] == "v1":
+		err = s.serveAPI(w, r, authUser, segments[2:])
+	case len(segments) == 2 && segments[0] == "attachments":
+		err = s.serveAttachmentDownload(w, r, authUser, segments[1])
+	case len(segments) == 4 && segments[2] == "archive":
+		err = s.serveSourceArchive(w, r, authUser, segments[0], segments[1], segments[3])
+	default:
+		err = errNotFound
+	}
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request, authUser *user, segments []string) error {
+	if _, ok := matchRoute(segments, "version"); ok {
+		return writeJSON(w, http.StatusOK, map[string]string{"version": Version})
+	}
+	if params, ok := matchRoute(segments, "users", "*", "tokens"); ok {
+		return s.serveTokens(w, r, authUser, params[0], "")
+	}
+	if params, ok := matchRoute(segments, "users", "*", "tokens", "*"); ok {
+		return s.serveTokens(w, r, authUser, params[0], params[1])
+	}
+	if len(segments) >= 3 && segments[0] == "repos" {
+		repo, err := s.lookupRepo(authUser, segments[1], segments[2])
+		if err != nil {
+			return err
+		}
+		return s.serveRepo(w, r, authUser, repo, segments[3:])
+	}
+	return errNotFound
+}
+
+func (s *Server) serveTokens(w http.ResponseWriter, r *http.Request, authUser *user, username, tokenName string) error {
+	// Gitea only allows managing tokens using basic auth.
+	if _, _, ok := r.BasicAuth(); !ok || authUser == nil || authUser.apiUser.UserName != username {
+		return errUnauthorized
+	}
+
+	switch {
+	case tokenName == "" && r.Method == http.MethodPost:
+		var opts gitea.CreateAccessTokenOption
+		if err := readJSON(r, &opts); err != nil {
+			return err
+		}
+		for _, t := range authUser.tokens {
+			if t.Name == opts.Name {
+				return newAPIError(http.StatusBadRequest, "access token name has been used already")
+			}
+		}
+		value := newRandomHex(20)
+		token := &gitea.AccessToken{
+			ID:             s.nextID(),
+			Name:           opts.Name,
+			Token:          value,
+			TokenLastEight: value[len(value)-8:],
+			Scopes:         opts.Scopes,
+		}
+		authUser.tokens = append(authUser.tokens, token)
+		return writeJSON(w, http.StatusCreated, token)
+
+	case tokenName != "" && r.Method == http.MethodDelete:
+		for i, t := range authUser.tokens {
+			if t.Name == tokenName || strconv.FormatInt(t.ID, 10) == tokenName {
+				authUser.tokens = append(authUser.tokens[:i], authUser.tokens[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return nil
+			}
+		}
+		return notFoundErr("access token %s does not exist", tokenName)
+	}
+	return errMethodNotAllowed
+}
+
+func (s *Server) serveRepo(w http.ResponseWriter, r *http.Request, authUser *user, repo *repo, segments []string) error {
+	if _, ok := matchRoute(segments); ok && r.Method == http.MethodGet {
+		return writeJSON(w, http.StatusOK, repo.apiRepo)
+	}
+	if _, ok := matchRoute(segments, "tags"); ok && r.Method == http.MethodGet {
+		return s.listTags(w, r, repo)
+	}
+	if params, ok := matchRoute(segments, "tags", "*"); ok && r.Method == http.MethodGet {
+		t := findTag(repo, params[0])
+		if t == nil {
+			return notFoundErr("tag %s does not exist", params[0])
+		}
+		return writeJSON(w, http.StatusOK, s.apiTag(repo, t))
+	}
+	if _, ok := matchRoute(segments, "commits"); ok && r.Method == http.MethodGet {
+		return s.listCommits(w, r, repo)
+	}
+	if len(segments) >= 2 && segments[0] == "compare" && r.Method == http.MethodGet && !s.compareDisabled {
+		// Refs can contain slashes, so the rest of the path is the compared refs.
+		return s.compareCommits(w, repo, strings.Join(segments[1:], "/"))
+	}
+	if _, ok := matchRoute(segments, "releases"); ok {
+		switch r.Method {
+		case http.MethodGet:
+			return s.listReleases(w, r, authUser, repo)
+		case http.MethodPost:
+			return s.postRelease(w, r, authUser, repo)
+		}
+		return errMethodNotAllowed
+	}
+	if params, ok := matchRoute(segments, "releases", "tags", "*"); ok {
+		rel := findReleaseByTag(repo, params[0])
+		if rel == nil || (rel.IsDraft && !canWrite(authUser, repo)) {
+			return notFoundErr("release with tag %s does not exist", params[0])
+		}
+		return s.serveRelease(w, r, authUser, repo, rel)
+	}
+	if params, ok := matchRoute(segments, "releases", "*"); ok {
+		rel, err := lookupRelease(authUser, repo, params[0])
+		if err != nil {
+			return err
+		}
+		return s.serveRelease(w, r, authUser, repo, rel)
+	}
+	if params, ok := matchRoute(segments, "releases", "*", "assets"); ok {
+		rel, err := lookupRelease(authUser, repo, params[0])
+		if err != nil {
+			return err
+		}
+		switch r.Method {
+		case http.MethodGet:
+			return listPage(w, r, s.URL, rel.Attachments)
+		case http.MethodPost:
+			return s.postAttachment(w, r, authUser, repo, rel)
+		}
+		return errMethodNotAllowed
+	}
+	if params, ok := matchRoute(segments, "releases", "*", "assets", "*"); ok {
+		rel, err := lookupRelease(authUser, repo, params[0])
+		if err != nil {
+			return err
+		}
+		return s.serveAttachment(w, r, authUser, repo, rel, params[1])
+	}
+	return errNotFound
+}
+
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request, repo *repo) error {
+	tags := make([]*gitea.Tag, 0, len(repo.tags))
+	// Newest tags first, matching Gitea.
+	for i := len(repo.tags) - 1; i >= 0; i-- {
+		tags = append(tags, s.apiTag(repo, repo.tags[i]))
+	}
+	return listPage(w, r, s.URL, tags)
+}
+
+func (s *Server) apiTag(repo *repo, t *tag) *gitea.Tag {
+	repoURL := s.URL + "/" + repo.apiRepo.FullName
+	return &gitea.Tag{
+		Name:       t.name,
+		ID:         t.sha,
+		Commit:     &gitea.CommitMeta{SHA: t.sha},
+		ZipballURL: repoURL + "/archive/" + t.name + ".zip",
+		TarballURL: repoURL + "/archive/" + t.name + ".tar.gz",
+	}
+}
+
+// listCommits lists the commits that are reachable from the ref in the sha query parameter (the default branch when
+// not set), newest first.
+func (s *Server) listCommits(w http.ResponseWriter, r *http.Request, repo *repo) error {
+	ref := r.URL.Query().Get("sha")
+	if ref == "" {
+		ref = repo.apiRepo.DefaultBranch
+	}
+	sha, ok := resolveRef(repo, ref)
+	if !ok {
+		return notFoundErr("ref %s does not exist", ref)
+	}
+
+	commits := []*gitea.Commit{}
+	for _, c := range history(repo, sha) {
+		commits = append(commits, s.apiCommit(repo, c))
+	}
+	return listPage(w, r, s.URL, commits)
+}
+
+// compareCommits lists the commits that are reachable from head, but not from base, newest first, like the three dot
+// compare of Gitea.
+func (s *Server) compareCommits(w http.ResponseWriter, repo *repo, basehead string) error {
+	base, head, ok := strings.Cut(basehead, "...")
+	if !ok {
+		return newAPIError(http.StatusNotFound, "invalid compare %s", basehead)
+	}
+	baseSHA, ok := resolveRef(repo, base)
+	if !ok {
+		return notFoundErr("ref %s does not exist", base)
+	}
+	headSHA, ok := resolveRef(repo, head)
+	if !ok {
+		return notFoundErr("ref %s does not exist", head)
+	}
+
+	reachableFromBase := map[string]bool{}
+	for _, c := range history(repo, baseSHA) {
+		reachableFromBase[c.sha] = true
+	}
+	commits := []*gitea.Commit{}
+	for _, c := range history(repo, headSHA) {
+		if reachableFromBase[c.sha] {
+			break
+		}
+		commits = append(commits, s.apiCommit(repo, c))
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_commits": len(commits),
+		"commits":       commits,
+	})
+}
+
+func (s *Server) apiCommit(repo *repo, c *commit) *gitea.Commit {
+	owner := repo.apiRepo.Owner
+	author := &gitea.CommitUser{
+		Identity: gitea.Identity{Name: owner.UserName, Email: owner.Email},
+		Date:     c.created.Format(time.RFC3339),
+	}
+	return &gitea.Commit{
+		CommitMeta: &gitea.CommitMeta{
+			URL:     fmt.Sprintf("%s/api/v1/repos/%s/git/commits/%s", s.URL, repo.apiRepo.FullName, c.sha),
+			SHA:     c.sha,
+			Created: c.created,
+		},
+		HTMLURL:    s.URL + "/" + repo.apiRepo.FullName + "/commit/" + c.sha,
+		RepoCommit: &gitea.RepoCommit{Message: c.message, Author: author, Committer: author},
+		Author:     clone(owner),
+		Committer:  clone(owner),
+	}
+}
+
+func (s *Server) listReleases(w http.ResponseWriter, r *http.Request, authUser *user, repo *repo) error {
+	q := r.URL.Query()
+	draftFilter, err := parseOptionalBool(q.Get("draft"))
+	if err != nil {
+		return err
+	}
+	preReleaseFilter, err := parseOptionalBool(q.Get("pre-release"))
+	if err != nil {
+		return err
+	}
+
+	releases := []*gitea.Release{}
+	for _, rel := range repo.releases {
+		// Drafts are only visible to users that can write to the repository.
+		if rel.IsDraft && !canWrite(authUser, repo) {
+			continue
+		}
+		if draftFilter != nil && rel.IsDraft != *draftFilter {
+			continue
+		}
+		if preReleaseFilter != nil && rel.IsPrerelease != *preReleaseFilter {
+			continue
+		}
+		releases = append(releases, rel)
+	}
+
+	// Gitea lists releases newest first, breaking ties by ID.
+	sort.SliceStable(releases, func(i, j int) bool {
+		if !releases[i].CreatedAt.Equal(releases[j].CreatedAt) {
+			return releases[i].CreatedAt.After(releases[j].CreatedAt)
+		}
+		return releases[i].ID > releases[j].ID
+	})
+	return listPage(w, r, s.URL, releases)
+}
+
+func (s *Server) postRelease(w http.ResponseWriter, r *http.Request, authUser *user, repo *repo) error {
+	if !canWrite(authUser, repo) {
+		return errForbidden
+	}
+	var opts gitea.CreateReleaseOption
+	if err := readJSON(r, &opts); err != nil {
+		return err
+	}
+	rel, err := s.createRelease(repo, authUser, opts)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, rel)
+}
+
+func (s *Server) serveRelease(w http.ResponseWriter, r *http.Request, authUser *user, repo *repo, rel *gitea.Release) error {
+	switch r.Method {
+	case http.MethodGet:
+		return writeJSON(w, http.StatusOK, rel)
+
+	case http.MethodPatch:
+		if !canWrite(authUser, repo) {
+			return errForbidden
+		}
+		var opts gitea.EditReleaseOption
+		if err := readJSON(r, &opts); err != nil {
+			return err
+		}
+		if err := s.editRelease(repo, rel, opts); err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusOK, rel)
+
+	case http.MethodDelete:
+		if !canWrite(authUser, repo) {
+			return errForbidden
+		}
+		for i, existing := range repo.releases {
+			if existing.ID == rel.ID {
+				repo.releases = append(repo.releases[:i], repo.releases[i+1:]...)
+				break
+			}
+		}
+		for _, a := range rel.Attachments {
+			delete(s.attachments, a.UUID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return errMethodNotAllowed
+}
+
+// editRelease applies the given edits to the release. Like Gitea, empty string fields are left unchanged.
+func (s *Server) editRelease(repo *repo, rel *gitea.Release, opts gitea.EditReleaseOption) error {
+	if opts.TagName != "" && opts.TagName != rel.TagName {
+		if findReleaseByTag(repo, opts.TagName) != nil {
+			return newAPIError(http.StatusConflict, "release with tag %s already exists", opts.TagName)
+		}
+		rel.TagName = opts.TagName
+	}
+	if opts.Target != "" {
+		rel.Target = opts.Target
+	}
+	if opts.Title != "" {
+		rel.Title = opts.Title
+	}
+	if opts.Note != "" {
+		rel.Note = opts.Note
+	}
+	if opts.IsPrerelease != nil {
+		rel.IsPrerelease = *opts.IsPrerelease
+	}
+	if opts.IsDraft != nil {
+		if rel.IsDraft && !*opts.IsDraft {
+			// Publishing a draft creates the tag and resets the publish time, as in Gitea.
+			published := s.now()
+			rel.CreatedAt = published
+			rel.PublishedAt = published
+		}
+		rel.IsDraft = *opts.IsDraft
+	}
+	if !rel.IsDraft {
+		if err := s.ensureTag(repo, rel.TagName, rel.Target); err != nil {
+			return err
+		}
+	}
+	s.setReleaseURLs(repo, rel)
+	return nil
+}
+
+func (s *Server) postAttachment(w http.ResponseWriter, r *http.Request, authUser *user, repo *repo, rel *gitea.Release) error {
+	if !canWrite(authUser, repo) {
+		return errForbidden
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return newAPIError(http.StatusBadRequest, "invalid multipart form: %s", err)
+	}
+	f, header, err := r.FormFile("attachment")
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, "missing attachment: %s", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, "could not read attachment: %s", err)
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = header.Filename
+	}
+	return writeJSON(w, http.StatusCreated, s.createAttachment(repo, rel, name, data))
+}
+
+func (s *Server) serveAttachment(w http.ResponseWriter, r *http.Request, authUser *user, repo *repo, rel *gitea.Release, idStr string) error {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return notFoundErr("attachment %s does not exist", idStr)
+	}
+	var found *gitea.Attachment
+	for _, a := range rel.Attachments {
+		if a.ID == id {
+			found = a
+		}
+	}
+	if found == nil {
+		return notFoundErr("attachment %d does not exist", id)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return writeJSON(w, http.StatusOK, found)
+
+	case http.MethodPatch:
+		if !canWrite(authUser, repo) {
+			return errForbidden
+		}
+		var opts gitea.EditAttachmentOptions
+		if err := readJSON(r, &opts); err != nil {
+			return err
+		}
+		if opts.Name != "" {
+			found.Name = opts.Name
+		}
+		return writeJSON(w, http.StatusCreated, found)
+
+	case http.MethodDelete:
+		if !canWrite(authUser, repo) {
+			return errForbidden
+		}
+		s.deleteAttachment(rel, id)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return errMethodNotAllowed
+}
+
+// serveAttachmentDownload serves the raw contents of the attachment with the given UUID. Attachments of private
+// repositories are only served to authenticated users with access to the repository.
+func (s *Server) serveAttachmentDownload(w http.ResponseWriter, r *http.Request, authUser *user, uuid string) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return errMethodNotAllowed
+	}
+	a, ok := s.attachments[uuid]
+	if !ok || !canRead(authUser, a.repo) {
+		return notFoundErr("attachment %s does not exist", uuid)
+	}
+
+	a.apiAttachment.DownloadCount++
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", a.apiAttachment.Name))
+	http.ServeContent(w, r, a.apiAttachment.Name, a.apiAttachment.Created, bytes.NewReader(a.data))
+	return nil
+}
+
+// authenticate returns the user that the request is authenticated as, supporting basic auth, token authorization
+// headers, and token query parameters. Returns nil without error for anonymous requests.
+func (s *Server) authenticate(r *http.Request) (*user, error) {
+	if username, password, ok := r.BasicAuth(); ok {
+		u, found := s.users[username]
+		if !found || u.password != password {
+			return nil, errUnauthorized
+		}
+		return u, nil
+	}
+
+	var token string
+	if authz := r.Header.Get("Authorization"); authz != "" {
+		fields := strings.Fields(authz)
+		if len(fields) != 2 || (!strings.EqualFold(fields[0], "token") && !strings.EqualFold(fields[0], "bearer")) {
+			return nil, errUnauthorized
+		}
+		token = fields[1]
+	} else if q := r.URL.Query(); q.Get("access_token") != "" {
+		token = q.Get("access_token")
+	} else if q.Get("token") != "" {
+		token = q.Get("token")
+	}
+	if token == "" {
+		return nil, nil
+	}
+
+	for _, u := range s.users {
+		for _, t := range u.tokens {
+			if t.Token == token {
+				return u, nil
+			}
+		}
+	}
+	return nil, errUnauthorized
+}
+
+// lookupRepo returns the requested repository, treating private repositories that the user can not access as not
+// existing, like Gitea does.
+func (s *Server) lookupRepo(authUser *user, owner, name string) (*repo, error) {
+	r, ok := s.repos[repoKey(owner, name)]
+	if !ok || !canRead(authUser, r) {
+		return nil, notFoundErr("repository %s does not exist", repoKey(owner, name))
+	}
+	return r, nil
+}
+
+func lookupRelease(authUser *user, repo *repo, idStr string) (*gitea.Release, error) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, notFoundErr("release %s does not exist", idStr)
+	}
+	rel := findRelease(repo, id)
+	if rel == nil || (rel.IsDraft && !canWrite(authUser, repo)) {
+		return nil, notFoundErr("release %d does not exist", id)
+	}
+	return rel, nil
+}
+
+func canRead(authUser *user, r *repo) bool {
+	return !r.apiRepo.Private || canWrite(authUser, r)
+}
+
+func canWrite(authUser *user, r *repo) bool {
+	return authUser != nil && authUser.apiUser.UserName == r.apiRepo.Owner.UserName
+}
+
+// listPage writes the requested page of the given items, along with the pagination Link and X-Total-Count headers in
+// the same format as Gitea.
+func listPage[T any](w http.ResponseWriter, r *http.Request, baseURL string, items []T) error {
+	q := r.URL.Query()
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageSize
+	} else if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	total := len(items)
+	lastPage := (total + limit - 1) / limit
+	links := []string{}
+	addLink := func(p int, rel string) {
+		q.Set("page", strconv.Itoa(p))
+		q.Set("limit", strconv.Itoa(limit))
+		links = append(links, fmt.Sprintf(`<%s%s?%s>; rel="%s"`, baseURL, r.URL.EscapedPath(), q.Encode(), rel))
+	}
+	if page < lastPage {
+		addLink(page+1, "next")
+		addLink(lastPage, "last")
+	}
+	if page > 1 {
+		addLink(1, "first")
+		addLink(page-1, "prev")
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ","))
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	start := min((page-1)*limit, total)
+	end := min(start+limit, total)
+	return writeJSON(w, http.StatusOK, items[start:end])
+}
+
+// matchRoute checks if the path segments match the given pattern, where "*" matches any single segment. Returns the
+// segments that matched the wildcards.
+func matchRoute(segments []string, pattern ...string) ([]string, bool) {
+	if len(segments) != len(pattern) {
+		return nil, false
+	}
+	params := []string{}
+	for i, p := range pattern {
+		switch {
+		case p == "*":
+			params = append(params, segments[i])
+		case p != segments[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// pathSegments splits the request path into unescaped segments, so that escaped slashes in tag names are preserved.
+func pathSegments(u *url.URL) ([]string, error) {
+	trimmed := strings.Trim(u.EscapedPath(), "/")
+	if trimmed == "" {
+		return []string{}, nil
+	}
+	segments := strings.Split(trimmed, "/")
+	for i, seg := range segments {
+		unescaped, err := url.PathUnescape(seg)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = unescaped
+	}
+	return segments, nil
+}
+
+func parseOptionalBool(raw string) (*bool, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	val, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, newAPIError(http.StatusUnprocessableEntity, "invalid boolean %q", raw)
+	}
+	return &val, nil
+}
+
+func readJSON(r *http.Request, out interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		return newAPIError(http.StatusUnprocessableEntity, "invalid request body: %s", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = newAPIError(http.StatusInternalServerError, "%s", err)
+	}
+	data, _ := json.Marshal(map[string]string{"message": apiErr.message})
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(apiErr.status)
+	w.Write(data)
+}
@@ -0,0 +1,459 @@
+package fakegitea
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"code.gitea.io/sdk/gitea"
+)
+
+const (
+	// Version is the Gitea server version reported by the fake server.
+	Version = "1.21.0"
+
+	// DefaultBranch is the branch that is created along with every repository.
+	DefaultBranch = "master"
+)
+
+// baseTime is the timestamp of the first event on the fake server. Every subsequent event advances the clock by one
+// second so that releases have distinct, deterministic timestamps.
+var baseTime = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Server is an in-memory fake of the Gitea API, served over HTTP using net/http/httptest. Use NewServer to construct
+// one, and Close to shut it down when done.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	lastID      int64
+	clock       time.Time
+	users       map[string]*user
+	repos       map[string]*repo
+	attachments map[string]*attachment
+
+	// compareDisabled removes the compare API, like Gitea versions before 1.22.
+	compareDisabled bool
+}
+
+type user struct {
+	apiUser  *gitea.User
+	password string
+	tokens   []*gitea.AccessToken
+}
+
+type repo struct {
+	apiRepo  *gitea.Repository
+	branches map[string]string
+	commits  []*commit
+	tags     []*tag
+	releases []*gitea.Release
+}
+
+type commit struct {
+	sha     string
+	parent  string
+	message string
+	created time.Time
+}
+
+type tag struct {
+	name string
+	sha  string
+}
+
+type attachment struct {
+	repo          *repo
+	release       *gitea.Release
+	apiAttachment *gitea.Attachment
+	data          []byte
+}
+
+// NewServer starts a new fake Gitea server with no users or repositories.
+func NewServer() *Server {
+	return newServer(httptest.NewServer)
+}
+
+// NewTLSServer starts a new fake Gitea server with no users or repositories, served over HTTPS using a self-signed
+// certificate. Use CertificatePEM to get the certificate for trusting the server.
+func NewTLSServer() *Server {
+	return newServer(httptest.NewTLSServer)
+}
+
+func newServer(start func(http.Handler) *httptest.Server) *Server {
+	s := &Server{
+		clock:       baseTime,
+		users:       map[string]*user{},
+		repos:       map[string]*repo{},
+		attachments: map[string]*attachment{},
+	}
+	s.Server = start(s)
+	return s
+}
+
+// CertificatePEM returns the PEM encoded certificate that the server uses for TLS. Returns an empty string if the
+// server is not served over TLS.
+func (s *Server) CertificatePEM() string {
+	cert := s.Certificate()
+	if cert == nil {
+		return ""
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
+
+// DisableCompareAPI removes the compare API from the server, to emulate Gitea versions before 1.22 which respond to it
+// with 404 Not Found.
+func (s *Server) DisableCompareAPI() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compareDisabled = true
+}
+
+// CreateUser registers a new user that can authenticate with the given password using basic auth.
+func (s *Server) CreateUser(username, password string) *gitea.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := &user{
+		apiUser: &gitea.User{
+			ID:       s.nextID(),
+			UserName: username,
+			Email:    username + "@example.com",
+			Created:  s.now(),
+		},
+		password: password,
+	}
+	s.users[username] = u
+	return clone(u.apiUser)
+}
+
+// CreateRepo creates a new repository owned by the given user, with a single initial commit on the default branch.
+func (s *Server) CreateRepo(owner, name string, private bool) (*gitea.Repository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[owner]
+	if !ok {
+		return nil, notFoundErr("user %s does not exist", owner)
+	}
+	key := repoKey(owner, name)
+	if _, exists := s.repos[key]; exists {
+		return nil, newAPIError(http.StatusConflict, "repository %s already exists", key)
+	}
+
+	r := &repo{
+		apiRepo: &gitea.Repository{
+			ID:            s.nextID(),
+			Owner:         clone(u.apiUser),
+			Name:          name,
+			FullName:      key,
+			Private:       private,
+			HTMLURL:       s.URL + "/" + key,
+			CloneURL:      s.URL + "/" + key + ".git",
+			DefaultBranch: DefaultBranch,
+			Created:       s.now(),
+		},
+		branches: map[string]string{},
+	}
+	s.repos[key] = r
+	s.commit(r, DefaultBranch, "initial commit")
+	return clone(r.apiRepo), nil
+}
+
+// CreateCommit records a new commit with the given message on the default branch of the repository, returning the
+// commit SHA.
+func (s *Server) CreateCommit(owner, repoName, message string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.repos[repoKey(owner, repoName)]
+	if !ok {
+		return "", notFoundErr("repository %s does not exist", repoKey(owner, repoName))
+	}
+	return s.commit(r, DefaultBranch, message), nil
+}
+
+// CreateBranchCommit records a new commit with the given message on the given branch of the repository, returning the
+// commit SHA.
+func (s *Server) CreateBranchCommit(owner, repoName, branch, message string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.repos[repoKey(owner, repoName)]
+	if !ok {
+		return "", notFoundErr("repository %s does not exist", repoKey(owner, repoName))
+	}
+	if _, ok := r.branches[branch]; !ok {
+		return "", notFoundErr("branch %s does not exist", branch)
+	}
+	return s.commit(r, branch, message), nil
+}
+
+// CreateBranch points the branch to the commit of the given ref of the repository, creating the branch if it doesn't
+// exist yet.
+func (s *Server) CreateBranch(owner, repoName, branch, ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.repos[repoKey(owner, repoName)]
+	if !ok {
+		return notFoundErr("repository %s does not exist", repoKey(owner, repoName))
+	}
+	sha, ok := resolveRef(r, ref)
+	if !ok {
+		return notFoundErr("ref %s does not exist", ref)
+	}
+	r.branches[branch] = sha
+	return nil
+}
+
+// CreateRelease creates a new release on the repository, published by the repository owner.
+func (s *Server) CreateRelease(owner, repoName string, opts gitea.CreateReleaseOption) (*gitea.Release, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.repos[repoKey(owner, repoName)]
+	if !ok {
+		return nil, notFoundErr("repository %s does not exist", repoKey(owner, repoName))
+	}
+	rel, err := s.createRelease(r, s.users[owner], opts)
+	if err != nil {
+		return nil, err
+	}
+	return clone(rel), nil
+}
+
+// CreateReleaseAttachment uploads the given data as an attachment to the release with the given ID.
+func (s *Server) CreateReleaseAttachment(owner, repoName string, releaseID int64, name string, data []byte) (*gitea.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.repos[repoKey(owner, repoName)]
+	if !ok {
+		return nil, notFoundErr("repository %s does not exist", repoKey(owner, repoName))
+	}
+	rel := findRelease(r, releaseID)
+	if rel == nil {
+		return nil, notFoundErr("release %d does not exist", releaseID)
+	}
+	return clone(s.createAttachment(r, rel, name, data)), nil
+}
+
+// commit appends a new commit to the given branch of the repository. Must be called with the lock held.
+func (s *Server) commit(r *repo, branch, message string) string {
+	created := s.now()
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d:%s", r.apiRepo.FullName, len(r.commits), message)))
+	c := &commit{
+		sha:     hex.EncodeToString(sum[:]),
+		parent:  r.branches[branch],
+		message: message,
+		created: created,
+	}
+	r.commits = append(r.commits, c)
+	r.branches[branch] = c.sha
+	r.apiRepo.Updated = created
+	return c.sha
+}
+
+// createRelease creates a new release on the repository, creating the tag if it doesn't exist yet. Must be called
+// with the lock held.
+func (s *Server) createRelease(r *repo, publisher *user, opts gitea.CreateReleaseOption) (*gitea.Release, error) {
+	if opts.TagName == "" {
+		return nil, newAPIError(http.StatusUnprocessableEntity, "tag_name is required")
+	}
+	if findReleaseByTag(r, opts.TagName) != nil {
+		return nil, newAPIError(http.StatusConflict, "release with tag %s already exists", opts.TagName)
+	}
+
+	target := opts.Target
+	if target == "" {
+		target = r.apiRepo.DefaultBranch
+	}
+	if !opts.IsDraft {
+		if err := s.ensureTag(r, opts.TagName, target); err != nil {
+			return nil, err
+		}
+	}
+
+	id := s.nextID()
+	created := s.now()
+	rel := &gitea.Release{
+		ID:           id,
+		TagName:      opts.TagName,
+		Target:       target,
+		Title:        opts.Title,
+		Note:         opts.Note,
+		IsDraft:      opts.IsDraft,
+		IsPrerelease: opts.IsPrerelease,
+		CreatedAt:    created,
+		PublishedAt:  created,
+		Attachments:  []*gitea.Attachment{},
+	}
+	if publisher != nil {
+		rel.Publisher = clone(publisher.apiUser)
+	}
+	s.setReleaseURLs(r, rel)
+	r.releases = append(r.releases, rel)
+	return rel, nil
+}
+
+// ensureTag creates the given tag pointing to the target ref if it doesn't already exist. Must be called with the
+// lock held.
+func (s *Server) ensureTag(r *repo, tagName, target string) error {
+	if findTag(r, tagName) != nil {
+		return nil
+	}
+	sha, ok := resolveRef(r, target)
+	if !ok {
+		return notFoundErr("target %s does not exist", target)
+	}
+	r.tags = append(r.tags, &tag{name: tagName, sha: sha})
+	return nil
+}
+
+// createAttachment stores the given data as a new attachment on the release. Must be called with the lock held.
+func (s *Server) createAttachment(r *repo, rel *gitea.Release, name string, data []byte) *gitea.Attachment {
+	uuid := newRandomHex(16)
+	apiAttachment := &gitea.Attachment{
+		ID:          s.nextID(),
+		Name:        name,
+		Size:        int64(len(data)),
+		Created:     s.now(),
+		UUID:        uuid,
+		DownloadURL: s.URL + "/attachments/" + uuid,
+	}
+	rel.Attachments = append(rel.Attachments, apiAttachment)
+	s.attachments[uuid] = &attachment{
+		repo:          r,
+		release:       rel,
+		apiAttachment: apiAttachment,
+		data:          data,
+	}
+	return apiAttachment
+}
+
+// deleteAttachment removes the attachment with the given ID from the release. Must be called with the lock held.
+func (s *Server) deleteAttachment(rel *gitea.Release, id int64) bool {
+	for i, a := range rel.Attachments {
+		if a.ID == id {
+			rel.Attachments = append(rel.Attachments[:i], rel.Attachments[i+1:]...)
+			delete(s.attachments, a.UUID)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) setReleaseURLs(r *repo, rel *gitea.Release) {
+	repoURL := s.URL + "/" + r.apiRepo.FullName
+	rel.URL = fmt.Sprintf("%s/api/v1/repos/%s/releases/%d", s.URL, r.apiRepo.FullName, rel.ID)
+	rel.HTMLURL = repoURL + "/releases/tag/" + rel.TagName
+	rel.TarURL = repoURL + "/archive/" + rel.TagName + ".tar.gz"
+	rel.ZipURL = repoURL + "/archive/" + rel.TagName + ".zip"
+}
+
+func (s *Server) nextID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+func (s *Server) now() time.Time {
+	s.clock = s.clock.Add(time.Second)
+	return s.clock
+}
+
+func repoKey(owner, name string) string {
+	return owner + "/" + name
+}
+
+func findRelease(r *repo, id int64) *gitea.Release {
+	for _, rel := range r.releases {
+		if rel.ID == id {
+			return rel
+		}
+	}
+	return nil
+}
+
+func findReleaseByTag(r *repo, tagName string) *gitea.Release {
+	for _, rel := range r.releases {
+		if rel.TagName == tagName {
+			return rel
+		}
+	}
+	return nil
+}
+
+func findTag(r *repo, tagName string) *tag {
+	for _, t := range r.tags {
+		if t.name == tagName {
+			return t
+		}
+	}
+	return nil
+}
+
+// resolveRef returns the commit SHA for the given branch, tag, or commit SHA.
+func resolveRef(r *repo, ref string) (string, bool) {
+	if sha, ok := r.branches[ref]; ok {
+		return sha, true
+	}
+	if t := findTag(r, ref); t != nil {
+		return t.sha, true
+	}
+	for _, c := range r.commits {
+		if c.sha == ref {
+			return c.sha, true
+		}
+	}
+	return "", false
+}
+
+// findCommit returns the commit with the given SHA, or nil if it doesn't exist.
+func findCommit(r *repo, sha string) *commit {
+	for _, c := range r.commits {
+		if c.sha == sha {
+			return c
+		}
+	}
+	return nil
+}
+
+// history returns the commits that are reachable from the commit with the given SHA, newest first. Since every fake
+// commit has a single parent, this is the chain of parents.
+func history(r *repo, sha string) []*commit {
+	out := []*commit{}
+	for c := findCommit(r, sha); c != nil; c = findCommit(r, c.parent) {
+		out = append(out, c)
+	}
+	return out
+}
+
+func newRandomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// clone does a deep copy of the given API object by round tripping through JSON, so that callers can not mutate the
+// server state.
+func clone[T any](in *T) *T {
+	data, err := json.Marshal(in)
+	if err != nil {
+		panic(err)
+	}
+	out := new(T)
+	if err := json.Unmarshal(data, out); err != nil {
+		panic(err)
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"bytes"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// TestGPGKey is a GPG key pair for signing and verifying release assets in tests.
+type TestGPGKey struct {
+	Entity *openpgp.Entity
+	// ArmoredPublicKey is the public key in the format of the verify_signature source parameter.
+	ArmoredPublicKey string
+	// ArmoredPrivateKey is the unencrypted private key in the format of the signing_key source parameter.
+	ArmoredPrivateKey string
+}
+
+// NewTestGPGKey generates a new GPG key pair for tests.
+func NewTestGPGKey() (*TestGPGKey, error) {
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey bytes.Buffer
+	w, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := entity.Serialize(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var privateKey bytes.Buffer
+	w, err = armor.Encode(&privateKey, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &TestGPGKey{
+		Entity:            entity,
+		ArmoredPublicKey:  publicKey.String(),
+		ArmoredPrivateKey: privateKey.String(),
+	}, nil
+}
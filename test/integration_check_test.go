@@ -3,37 +3,75 @@ package test
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
 
+	gogitea "code.gitea.io/sdk/gitea"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/resource"
+	"github.com/yorinasub17/concourse-gitea-release-resource/test/fakegitea"
 )
 
 var _ = Describe("Integration Check", func() {
 	var (
-		inputRepo         string
-		semverConstraint  string
-		includePreRelease bool = true
-		priorVersionTag   string
+		inputURL           string
+		inputToken         string
+		inputRepo          string
+		semverConstraint   string
+		includePreRelease  bool = true
+		priorVersionTag    string
+		priorVersionID     string
+		tagFilter          string
+		targetCommitish    string
+		titleRegex         string
+		bodyRegex          string
+		requireAssets      []string
+		releaseAuthor      []string
+		orderBy            string
+		drafts             string
+		checkMode          string
+		onMissingVersion   string
+		caCert             string
+		insecureSkipVerify bool
+		expectCheckFailure bool
 
 		output []resource.Version
+		stderr bytes.Buffer
 	)
 
+	BeforeEach(func() {
+		inputURL = serverURL
+		inputToken = accessToken
+	})
+
 	JustBeforeEach(func() {
 		checkRequest := resource.CheckRequest{
 			Source: resource.Source{
-				GiteaURL:         ServerURL,
-				Owner:            Username,
-				Repository:       inputRepo,
-				AccessToken:      accessToken,
-				PreRelease:       includePreRelease,
-				SemverConstraint: semverConstraint,
+				GiteaURL:           inputURL,
+				Owner:              Username,
+				Repository:         inputRepo,
+				AccessToken:        inputToken,
+				PreRelease:         includePreRelease,
+				SemverConstraint:   semverConstraint,
+				TagFilter:          tagFilter,
+				TargetCommitish:    targetCommitish,
+				TitleRegex:         titleRegex,
+				BodyRegex:          bodyRegex,
+				RequireAssets:      requireAssets,
+				ReleaseAuthor:      releaseAuthor,
+				OrderBy:            orderBy,
+				Drafts:             drafts,
+				CheckMode:          checkMode,
+				OnMissingVersion:   onMissingVersion,
+				CACert:             caCert,
+				InsecureSkipVerify: insecureSkipVerify,
 			},
 			Version: resource.Version{
+				ID:  priorVersionID,
 				Tag: priorVersionTag,
 			},
 		}
@@ -42,11 +80,17 @@ var _ = Describe("Integration Check", func() {
 		Ω(err).ShouldNot(HaveOccurred())
 
 		var stdout bytes.Buffer
-		cmd := exec.Command("docker", "run", "-i", "--rm", "--network", "host", imgTag, "/opt/resource/check")
+		cmd := resourceCommand("check", "")
 		cmd.Stdin = bytes.NewReader(jsonBytes)
 		cmd.Stdout = &stdout
-		cmd.Stderr = os.Stderr
-		Ω(cmd.Run()).To(Succeed())
+		stderr.Reset()
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		runErr := cmd.Run()
+		if expectCheckFailure {
+			Ω(runErr).Should(HaveOccurred())
+			return
+		}
+		Ω(runErr).ShouldNot(HaveOccurred())
 
 		outputStr := strings.TrimSpace(stdout.String())
 		Ω(json.Unmarshal([]byte(outputStr), &output)).To(Succeed())
@@ -57,7 +101,58 @@ var _ = Describe("Integration Check", func() {
 		inputRepo = ""
 		semverConstraint = ""
 		priorVersionTag = ""
+		priorVersionID = ""
 		includePreRelease = true
+		tagFilter = ""
+		targetCommitish = ""
+		titleRegex = ""
+		bodyRegex = ""
+		requireAssets = nil
+		releaseAuthor = nil
+		orderBy = ""
+		drafts = ""
+		checkMode = ""
+		onMissingVersion = ""
+		expectCheckFailure = false
+		caCert = ""
+		insecureSkipVerify = false
+	})
+
+	Context("when gitea is served over TLS with a self-signed certificate", func() {
+		var tlsServer *fakegitea.Server
+
+		BeforeEach(func() {
+			srv, err := NewFakeTLSServer()
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(srv.Close)
+			tlsServer = srv
+
+			inputURL = srv.URL
+			inputToken = ""
+			inputRepo = PublicRepo
+		})
+
+		Context("and the CA cert is provided", func() {
+			BeforeEach(func() {
+				caCert = tlsServer.CertificatePEM()
+			})
+
+			It("returns latest release version", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+
+		Context("and TLS verification is skipped", func() {
+			BeforeEach(func() {
+				insecureSkipVerify = true
+			})
+
+			It("returns latest release version", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.1"))
+			})
+		})
 	})
 
 	Context("when this is the first time that the resource has been run", func() {
@@ -177,10 +272,10 @@ var _ = Describe("Integration Check", func() {
 			})
 
 			Context("and prerelease included", func() {
-				It("returns all newer releases", func() {
+				It("returns all newer releases from oldest to newest", func() {
 					Ω(len(output)).Should(Equal(2))
-					Ω(output[0].Tag).Should(Equal("v0.0.1"))
-					Ω(output[1].Tag).Should(Equal("v0.0.1-alpha.1"))
+					Ω(output[0].Tag).Should(Equal("v0.0.1-alpha.1"))
+					Ω(output[1].Tag).Should(Equal("v0.0.1"))
 				})
 			})
 
@@ -218,4 +313,431 @@ var _ = Describe("Integration Check", func() {
 			})
 		})
 	})
+
+	Context("when releases have tags that are not semver", func() {
+		BeforeEach(func() {
+			inputRepo = NonSemverRepo
+		})
+
+		Context("and this is the first time that the resource has been run", func() {
+			Context("without tag filter", func() {
+				It("returns latest release in gitea order", func() {
+					Ω(len(output)).Should(Equal(1))
+					Ω(output[0].Tag).Should(Equal("release-2024.04.3"))
+				})
+			})
+
+			Context("with tag filter ordered by semver", func() {
+				BeforeEach(func() {
+					tagFilter = "^release-(.+)$"
+					orderBy = "semver"
+				})
+
+				It("returns release with the latest captured version", func() {
+					Ω(len(output)).Should(Equal(1))
+					Ω(output[0].Tag).Should(Equal("release-2024.05.2"))
+				})
+			})
+
+			Context("with tag filter and semver constraint", func() {
+				BeforeEach(func() {
+					tagFilter = "^release-(.+)$"
+					orderBy = "semver"
+					semverConstraint = "< 2024.05.0"
+				})
+
+				It("returns latest matching", func() {
+					Ω(len(output)).Should(Equal(1))
+					Ω(output[0].Tag).Should(Equal("release-2024.04.3"))
+				})
+			})
+
+			Context("with tag filter excluding all", func() {
+				BeforeEach(func() {
+					tagFilter = "^nightly-"
+				})
+
+				It("returns no versions", func() {
+					Ω(output).Should(BeEmpty())
+				})
+			})
+		})
+
+		Context("and there are prior versions", func() {
+			Context("with tag filter ordered by semver", func() {
+				BeforeEach(func() {
+					priorVersionTag = "release-2024.05.1"
+					tagFilter = "^release-(.+)$"
+					orderBy = "semver"
+				})
+
+				It("returns releases with a newer captured version", func() {
+					Ω(len(output)).Should(Equal(1))
+					Ω(output[0].Tag).Should(Equal("release-2024.05.2"))
+				})
+			})
+
+			Context("with tag filter ordered by publish time", func() {
+				BeforeEach(func() {
+					priorVersionTag = "release-2024.05.1"
+					tagFilter = "^release-(.+)$"
+					orderBy = "published_at"
+				})
+
+				It("returns releases published later from oldest to newest", func() {
+					Ω(len(output)).Should(Equal(2))
+					Ω(output[0].Tag).Should(Equal("release-2024.05.2"))
+					Ω(output[1].Tag).Should(Equal("release-2024.04.3"))
+				})
+			})
+
+			Context("without tag filter", func() {
+				BeforeEach(func() {
+					priorVersionTag = "build-100"
+				})
+
+				It("returns releases published later from oldest to newest", func() {
+					Ω(len(output)).Should(Equal(2))
+					Ω(output[0].Tag).Should(Equal("release-2024.05.2"))
+					Ω(output[1].Tag).Should(Equal("release-2024.04.3"))
+				})
+			})
+		})
+	})
+
+	Context("when the prior version was deleted", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+			priorVersionTag = "v0.0.0"
+			priorVersionID = "999999"
+		})
+
+		Context("and the policy is the default", func() {
+			It("returns latest release version", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.1"))
+				Ω(stderr.String()).Should(ContainSubstring("release 999999 with tag v0.0.0 does not exist"))
+			})
+		})
+
+		Context("and the policy is empty", func() {
+			BeforeEach(func() {
+				onMissingVersion = "empty"
+			})
+
+			It("returns no versions", func() {
+				Ω(output).Should(BeEmpty())
+			})
+		})
+
+		Context("and the policy is fail", func() {
+			BeforeEach(func() {
+				onMissingVersion = "fail"
+				expectCheckFailure = true
+			})
+
+			It("fails the check", func() {
+				Ω(stderr.String()).Should(ContainSubstring("release 999999 with tag v0.0.0 does not exist"))
+			})
+		})
+	})
+
+	Context("when the prior version has a release ID", func() {
+		var releaseID string
+
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+
+			rel, _, err := giteaClt.GetReleaseByTag(Username, PublicRepo, "v0.0.0")
+			Ω(err).ShouldNot(HaveOccurred())
+			releaseID = strconv.FormatInt(rel.ID, 10)
+			priorVersionID = releaseID
+		})
+
+		Context("and the tag still matches", func() {
+			BeforeEach(func() {
+				priorVersionTag = "v0.0.0"
+			})
+
+			It("returns all newer releases from oldest to newest", func() {
+				Ω(len(output)).Should(Equal(2))
+				Ω(output[0].Tag).Should(Equal("v0.0.1-alpha.1"))
+				Ω(output[1].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+
+		Context("and the release was re-tagged", func() {
+			BeforeEach(func() {
+				priorVersionTag = "v0.0.0-retagged"
+				onMissingVersion = "fail"
+				expectCheckFailure = true
+			})
+
+			It("fails the check", func() {
+				Ω(stderr.String()).Should(ContainSubstring(
+					"release " + releaseID + " was re-tagged from v0.0.0-retagged to v0.0.0",
+				))
+			})
+		})
+	})
+
+	Context("when check mode is every", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+			checkMode = "every"
+		})
+
+		Context("and this is the first time that the resource has been run", func() {
+			It("returns all releases from oldest to newest", func() {
+				Ω(len(output)).Should(Equal(4))
+				Ω(output[0].Tag).Should(Equal("v0.0.0-alpha.1"))
+				Ω(output[1].Tag).Should(Equal("v0.0.0"))
+				Ω(output[2].Tag).Should(Equal("v0.0.1-alpha.1"))
+				Ω(output[3].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+
+		Context("and there are prior versions", func() {
+			BeforeEach(func() {
+				priorVersionTag = "v0.0.0"
+			})
+
+			It("returns the current release and all newer releases from oldest to newest", func() {
+				Ω(len(output)).Should(Equal(3))
+				Ω(output[0].Tag).Should(Equal("v0.0.0"))
+				Ω(output[1].Tag).Should(Equal("v0.0.1-alpha.1"))
+				Ω(output[2].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+
+		Context("and tags are not semver", func() {
+			BeforeEach(func() {
+				inputRepo = NonSemverRepo
+				tagFilter = "^release-(.+)$"
+				orderBy = "semver"
+			})
+
+			It("returns all matching releases in semver order", func() {
+				Ω(len(output)).Should(Equal(3))
+				Ω(output[0].Tag).Should(Equal("release-2024.04.3"))
+				Ω(output[1].Tag).Should(Equal("release-2024.05.1"))
+				Ω(output[2].Tag).Should(Equal("release-2024.05.2"))
+			})
+		})
+	})
+
+	Context("when check mode is latest", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+			checkMode = "latest"
+			priorVersionTag = "v0.0.0"
+		})
+
+		It("returns only the latest release version", func() {
+			Ω(len(output)).Should(Equal(1))
+			Ω(output[0].Tag).Should(Equal("v0.0.1"))
+		})
+	})
+
+	Context("when there is a release cut from a release branch", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+
+			createBranch(PublicRepo, "release/0.1")
+			rel, _, err := giteaClt.CreateRelease(Username, PublicRepo, gogitea.CreateReleaseOption{
+				TagName: "v0.1.0",
+				Target:  "release/0.1",
+				Title:   "v0.1.0",
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(func() {
+				_, err := giteaClt.DeleteRelease(Username, PublicRepo, rel.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("and the target commitish matches the release branch", func() {
+			BeforeEach(func() {
+				targetCommitish = "release/*"
+			})
+
+			It("returns the release cut from the release branch", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.1.0"))
+			})
+		})
+
+		Context("and the target commitish matches no branch", func() {
+			BeforeEach(func() {
+				targetCommitish = "hotfix/*"
+			})
+
+			It("returns no versions", func() {
+				Ω(output).Should(BeEmpty())
+			})
+		})
+	})
+
+	Context("when filtering releases by author", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+		})
+
+		Context("and the releases are published by one of the authors", func() {
+			BeforeEach(func() {
+				releaseAuthor = []string{"release-bot", Username}
+			})
+
+			It("returns latest release version", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+
+		Context("and the releases are published by other users", func() {
+			BeforeEach(func() {
+				releaseAuthor = []string{"release-bot"}
+			})
+
+			It("returns no versions", func() {
+				Ω(output).Should(BeEmpty())
+			})
+		})
+	})
+
+	Context("when the latest release is still being published", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+
+			rel, _, err := giteaClt.CreateRelease(Username, PublicRepo, gogitea.CreateReleaseOption{
+				TagName: "v0.0.2",
+				Target:  "master",
+				Title:   "v0.0.2 (WIP)",
+				Note:    "work in progress",
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(func() {
+				_, err := giteaClt.DeleteRelease(Username, PublicRepo, rel.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("and there are no content filters", func() {
+			It("returns the release that is being published", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.2"))
+			})
+		})
+
+		Context("and the title regex excludes it", func() {
+			BeforeEach(func() {
+				titleRegex = `^v[0-9.]+$`
+			})
+
+			It("returns latest release with a matching title", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+
+		Context("and the body regex excludes it", func() {
+			BeforeEach(func() {
+				bodyRegex = `^release `
+			})
+
+			It("returns latest release with a matching body", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+
+		Context("and assets are required", func() {
+			BeforeEach(func() {
+				requireAssets = []string{"tag", "asset*"}
+			})
+
+			It("returns latest release with all the required assets", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+	})
+
+	Context("when there is a draft release", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+
+			rel, _, err := giteaClt.CreateRelease(Username, PublicRepo, gogitea.CreateReleaseOption{
+				TagName: "v0.0.2",
+				Target:  "master",
+				Title:   "v0.0.2",
+				IsDraft: true,
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(func() {
+				_, err := giteaClt.DeleteRelease(Username, PublicRepo, rel.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("and drafts are excluded by default", func() {
+			It("returns latest published release version", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.1"))
+			})
+		})
+
+		Context("and drafts are included", func() {
+			BeforeEach(func() {
+				drafts = "include"
+			})
+
+			It("returns draft release version", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.2"))
+			})
+
+			Context("with prior versions", func() {
+				BeforeEach(func() {
+					priorVersionTag = "v0.0.1"
+				})
+
+				It("returns draft release version", func() {
+					Ω(len(output)).Should(Equal(1))
+					Ω(output[0].Tag).Should(Equal("v0.0.2"))
+					Ω(output[0].ID).ShouldNot(BeEmpty())
+				})
+			})
+		})
+
+		Context("and only drafts are requested", func() {
+			BeforeEach(func() {
+				drafts = "only"
+				priorVersionTag = "v0.0.0"
+			})
+
+			It("returns only draft release versions", func() {
+				Ω(len(output)).Should(Equal(1))
+				Ω(output[0].Tag).Should(Equal("v0.0.2"))
+			})
+		})
+	})
 })
+
+// createBranch creates a branch pointing to the head of the default branch of the repository.
+func createBranch(repo, branch string) {
+	if fakeServer != nil {
+		Ω(fakeServer.CreateBranch(Username, repo, branch, fakegitea.DefaultBranch)).Should(Succeed())
+		return
+	}
+
+	_, _, err := giteaClt.CreateBranch(Username, repo, gogitea.CreateBranchOption{
+		BranchName:    branch,
+		OldBranchName: fakegitea.DefaultBranch,
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+	DeferCleanup(func() {
+		_, _, err := giteaClt.DeleteRepoBranch(Username, repo, branch)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+}
@@ -1,19 +1,23 @@
 package test
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
-	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 
-	"github.com/gruntwork-io/go-commons/shell"
+	gogitea "code.gitea.io/sdk/gitea"
+	"github.com/ProtonMail/go-crypto/openpgp"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"github.com/yorinasub17/concourse-gitea-release-resource/internal/archive"
 	"github.com/yorinasub17/concourse-gitea-release-resource/internal/resource"
 )
 
@@ -26,6 +30,8 @@ var (
 		"tag",
 		"body",
 		"timestamp",
+		"release.json",
+		"assets.json",
 	}
 	expectedReleaseAssets = []string{
 		"tag",
@@ -38,10 +44,19 @@ var _ = Describe("Integration In", func() {
 	var (
 		inputRepo       string
 		inputVersionTag string
+		inputVersionID  string
 		globs           []string
+		parallelism     int
+		verifyChecksums bool
+		unpack          bool
+		includeSource   bool
+		skipDownload    bool
+		sigConfig       *resource.SignatureConfig
+		expectInFailure bool
 
 		output    resource.InOutResponse
 		outputDir string
+		stderr    bytes.Buffer
 	)
 
 	JustBeforeEach(func() {
@@ -51,16 +66,26 @@ var _ = Describe("Integration In", func() {
 
 		inRequest := resource.InRequest{
 			Source: resource.Source{
-				GiteaURL:    ServerURL,
+				GiteaURL:    serverURL,
 				Owner:       Username,
 				Repository:  inputRepo,
 				AccessToken: accessToken,
+
+				VerifySignature: sigConfig,
 			},
 			Version: &resource.Version{
+				ID:  inputVersionID,
 				Tag: inputVersionTag,
 			},
 			Params: resource.InParams{
-				Globs: globs,
+				Globs:           globs,
+				Parallelism:     parallelism,
+				VerifyChecksums: verifyChecksums,
+				Unpack:          unpack,
+
+				IncludeSourceZip:     includeSource,
+				IncludeSourceTarball: includeSource,
+				SkipDownload:         skipDownload,
 			},
 		}
 
@@ -68,29 +93,18 @@ var _ = Describe("Integration In", func() {
 		Ω(err).ShouldNot(HaveOccurred())
 
 		var stdout bytes.Buffer
-		cmd := exec.Command(
-			"docker", "run",
-			"-i", "--rm", "--network", "host",
-			"-v", fmt.Sprintf("%s:/output", outputDir),
-			imgTag, "/opt/resource/in", "/output",
-		)
+		cmd := resourceCommand("in", outputDir, outputDir)
 		cmd.Stdin = bytes.NewReader(jsonBytes)
 		cmd.Stdout = &stdout
-		cmd.Stderr = os.Stderr
-		Ω(cmd.Run()).To(Succeed())
-
-		// chown the files to the current UID and GID so that it can be removed later. We use a docker container so that
-		// we can run with root without prompting for sudo password.
-		u, err := user.Current()
-		Ω(err).ShouldNot(HaveOccurred())
-		Ω(
-			shell.RunShellCommand(
-				shell.NewShellOptions(),
-				"docker", "run",
-				"--rm", "-v", fmt.Sprintf("%s:/output", outputDir),
-				"alpine:3", "chown", "-R", fmt.Sprintf("%s:%s", u.Uid, u.Gid), "/output",
-			),
-		).ShouldNot(HaveOccurred())
+		stderr.Reset()
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		runErr := cmd.Run()
+		fixOwnership(outputDir)
+		if expectInFailure {
+			Ω(runErr).Should(HaveOccurred())
+			return
+		}
+		Ω(runErr).ShouldNot(HaveOccurred())
 
 		outputStr := strings.TrimSpace(stdout.String())
 		Ω(json.Unmarshal([]byte(outputStr), &output)).To(Succeed())
@@ -100,7 +114,15 @@ var _ = Describe("Integration In", func() {
 	JustAfterEach(func() {
 		inputRepo = ""
 		inputVersionTag = ""
+		inputVersionID = ""
 		globs = []string{}
+		parallelism = 0
+		verifyChecksums = false
+		unpack = false
+		includeSource = false
+		skipDownload = false
+		sigConfig = nil
+		expectInFailure = false
 
 		Ω(os.RemoveAll(outputDir)).To(Succeed())
 	})
@@ -142,6 +164,33 @@ var _ = Describe("Integration In", func() {
 			Ω(os.ReadFile(filepath.Join(outputDir, "body"))).To(Equal([]byte("release v0.0.0")))
 		})
 
+		It("outputs the release and its assets as JSON", func() {
+			releaseJSON, err := os.ReadFile(filepath.Join(outputDir, "release.json"))
+			Ω(err).ShouldNot(HaveOccurred())
+			var rel gogitea.Release
+			Ω(json.Unmarshal(releaseJSON, &rel)).To(Succeed())
+			Ω(rel.TagName).Should(Equal("v0.0.0"))
+			Ω(rel.Publisher.UserName).Should(Equal(Username))
+
+			assetsJSON, err := os.ReadFile(filepath.Join(outputDir, "assets.json"))
+			Ω(err).ShouldNot(HaveOccurred())
+			var attachments []*gogitea.Attachment
+			Ω(json.Unmarshal(assetsJSON, &attachments)).To(Succeed())
+			names := []string{}
+			for _, a := range attachments {
+				names = append(names, a.Name)
+				Ω(a.Size).Should(BeNumerically(">", 0))
+			}
+			Ω(names).Should(ConsistOf(expectedReleaseAssets))
+		})
+
+		It("outputs the author and asset count as metadata", func() {
+			Ω(output.Metadata).Should(ContainElements(
+				resource.MetadataPair{Name: "author", Value: Username},
+				resource.MetadataPair{Name: "assets", Value: "3"},
+			))
+		})
+
 		It("outputs release assets", func() {
 			for _, fname := range expectedReleaseAssets {
 				_, err := os.Stat(filepath.Join(outputDir, "assets", fname))
@@ -161,6 +210,19 @@ var _ = Describe("Integration In", func() {
 			Ω(os.ReadFile(filepath.Join(outputDir, "assets", "asset2"))).Should(Equal(asset2Bytes))
 		})
 
+		Context("with parallelism", func() {
+			BeforeEach(func() {
+				parallelism = 2
+			})
+
+			It("outputs release assets", func() {
+				for _, fname := range expectedReleaseAssets {
+					_, err := os.Stat(filepath.Join(outputDir, "assets", fname))
+					Ω(err).ShouldNot(HaveOccurred())
+				}
+			})
+		})
+
 		Context("with globs", func() {
 			BeforeEach(func() {
 				globs = []string{"t*"}
@@ -176,5 +238,414 @@ var _ = Describe("Integration In", func() {
 				}
 			})
 		})
+
+		Context("with skip download", func() {
+			BeforeEach(func() {
+				skipDownload = true
+			})
+
+			It("outputs only release metadata", func() {
+				for _, fname := range metadataAssets {
+					Ω(filepath.Join(outputDir, fname)).Should(BeAnExistingFile())
+				}
+				Ω(filepath.Join(outputDir, "assets")).ShouldNot(BeAnExistingFile())
+			})
+		})
+
+		Context("with source archives", func() {
+			BeforeEach(func() {
+				includeSource = true
+			})
+
+			It("outputs the source archives of the tag", func() {
+				expectSourceArchive(filepath.Join(outputDir, "source.zip"), PublicRepo)
+				expectSourceArchive(filepath.Join(outputDir, "source.tar.gz"), PublicRepo)
+			})
+		})
+	})
+
+	Context("when release is in a private repository and has assets", func() {
+		const privateAssetStr = "This is an asset on a private repository"
+
+		BeforeEach(func() {
+			inputRepo = PrivateRepo
+			inputVersionTag = "v0.0.1"
+
+			rel, _, err := giteaClt.GetReleaseByTag(Username, PrivateRepo, inputVersionTag)
+			Ω(err).ShouldNot(HaveOccurred())
+			attc, _, err := giteaClt.CreateReleaseAttachment(
+				Username, PrivateRepo, rel.ID, strings.NewReader(privateAssetStr), "privateasset",
+			)
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(func() {
+				_, err := giteaClt.DeleteReleaseAttachment(Username, PrivateRepo, rel.ID, attc.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		It("downloads release assets using the access token", func() {
+			Ω(os.ReadFile(filepath.Join(outputDir, "assets", "privateasset"))).Should(Equal([]byte(privateAssetStr)))
+		})
+
+		Context("with source archives", func() {
+			BeforeEach(func() {
+				includeSource = true
+			})
+
+			It("downloads the source archives using the access token", func() {
+				expectSourceArchive(filepath.Join(outputDir, "source.zip"), PrivateRepo)
+				expectSourceArchive(filepath.Join(outputDir, "source.tar.gz"), PrivateRepo)
+			})
+		})
+	})
+
+	Context("when release is a draft", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+			inputVersionTag = "v0.0.2"
+
+			rel, _, err := giteaClt.CreateRelease(Username, PublicRepo, gogitea.CreateReleaseOption{
+				TagName: inputVersionTag,
+				Target:  "master",
+				Title:   inputVersionTag,
+				Note:    "draft release " + inputVersionTag,
+				IsDraft: true,
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			inputVersionID = strconv.FormatInt(rel.ID, 10)
+			DeferCleanup(func() {
+				_, err := giteaClt.DeleteRelease(Username, PublicRepo, rel.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		It("outputs draft release metadata", func() {
+			Ω(os.ReadFile(filepath.Join(outputDir, "id"))).To(Equal([]byte(inputVersionID)))
+			Ω(os.ReadFile(filepath.Join(outputDir, "tag"))).To(Equal([]byte("v0.0.2")))
+			Ω(os.ReadFile(filepath.Join(outputDir, "body"))).To(Equal([]byte("draft release v0.0.2")))
+		})
+	})
+
+	Context("when release has a checksum manifest", func() {
+		const (
+			checksumAssetStr = "This is a checksummed asset"
+			// sha256sum of checksumAssetStr
+			checksumAssetSum = "1d84bab04ea01fd9f51ce2eb54c83d68ae8ffe192c4f51294b86a8eebc2f49c8"
+		)
+
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+			inputVersionTag = "checksums"
+			verifyChecksums = true
+		})
+
+		Context("that matches the assets", func() {
+			BeforeEach(func() {
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"checksummed": checksumAssetStr,
+					"SHA256SUMS":  checksumAssetSum + "  checksummed\n",
+				})
+			})
+
+			It("verifies and outputs release assets", func() {
+				Ω(os.ReadFile(filepath.Join(outputDir, "assets", "checksummed"))).Should(Equal([]byte(checksumAssetStr)))
+			})
+		})
+
+		Context("that does not match the assets", func() {
+			BeforeEach(func() {
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"checksummed": checksumAssetStr,
+					"SHA256SUMS":  strings.Repeat("0", 64) + "  checksummed\n",
+				})
+				expectInFailure = true
+			})
+
+			It("fails the get", func() {
+				Ω(stderr.String()).Should(ContainSubstring("sha256 checksum mismatch for checksummed"))
+			})
+		})
+	})
+
+	Context("when release has no checksum manifest and checksums are verified", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+			inputVersionTag = "v0.0.0"
+			verifyChecksums = true
+			expectInFailure = true
+		})
+
+		It("fails the get", func() {
+			Ω(stderr.String()).Should(ContainSubstring("has no checksum manifest"))
+		})
+	})
+
+	Context("when signatures are verified", func() {
+		const (
+			signedAssetStr = "This is a signed asset"
+			// sha256sum of signedAssetStr
+			signedAssetSum = "26895cda21a37eea0e73f8492209972d2bdc9ab7ea6e508fd23b568cac960c0f"
+		)
+
+		var (
+			key    *TestGPGKey
+			signer *openpgp.Entity
+		)
+
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+			inputVersionTag = "signatures"
+
+			rawKey, err := NewTestGPGKey()
+			Ω(err).ShouldNot(HaveOccurred())
+			key = rawKey
+			signer = key.Entity
+			sigConfig = &resource.SignatureConfig{GPGPublicKeys: []string{key.ArmoredPublicKey}}
+		})
+
+		Context("and asset has a valid signature", func() {
+			BeforeEach(func() {
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"signed":     signedAssetStr,
+					"signed.asc": gpgSign(signer, signedAssetStr),
+				})
+			})
+
+			It("verifies and outputs release assets", func() {
+				Ω(os.ReadFile(filepath.Join(outputDir, "assets", "signed"))).Should(Equal([]byte(signedAssetStr)))
+			})
+		})
+
+		Context("and asset is listed in a signed checksum manifest", func() {
+			BeforeEach(func() {
+				manifest := signedAssetSum + "  signed\n"
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"signed":         signedAssetStr,
+					"SHA256SUMS":     manifest,
+					"SHA256SUMS.asc": gpgSign(signer, manifest),
+				})
+			})
+
+			It("verifies and outputs release assets", func() {
+				Ω(os.ReadFile(filepath.Join(outputDir, "assets", "signed"))).Should(Equal([]byte(signedAssetStr)))
+			})
+		})
+
+		Context("and release was put with a signing key and checksums", func() {
+			BeforeEach(func() {
+				verifyChecksums = true
+				putSignedRelease(inputVersionTag, key.ArmoredPrivateKey, map[string]string{
+					"signed": signedAssetStr,
+				})
+			})
+
+			It("verifies checksums and signatures of the release assets", func() {
+				assetsDir := filepath.Join(outputDir, "assets")
+				Ω(os.ReadFile(filepath.Join(assetsDir, "signed"))).Should(Equal([]byte(signedAssetStr)))
+				Ω(filepath.Join(assetsDir, "signed.asc")).Should(BeARegularFile())
+				Ω(filepath.Join(assetsDir, "SHA256SUMS.asc")).Should(BeARegularFile())
+			})
+		})
+
+		Context("and asset has an invalid signature", func() {
+			BeforeEach(func() {
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"signed":     signedAssetStr,
+					"signed.asc": gpgSign(signer, "other contents"),
+				})
+				expectInFailure = true
+			})
+
+			It("fails the get naming the asset", func() {
+				Ω(stderr.String()).Should(ContainSubstring("signature verification failed for signed"))
+			})
+		})
+
+		Context("and asset is not signed", func() {
+			BeforeEach(func() {
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"unsigned": signedAssetStr,
+				})
+				expectInFailure = true
+			})
+
+			It("fails the get naming the asset", func() {
+				Ω(stderr.String()).Should(ContainSubstring("asset unsigned is not signed"))
+			})
+		})
+	})
+
+	Context("when archives are unpacked", func() {
+		BeforeEach(func() {
+			inputRepo = PublicRepo
+			inputVersionTag = "unpack"
+			unpack = true
+		})
+
+		Context("and release has tar.gz and zip assets", func() {
+			BeforeEach(func() {
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"bundle.tar.gz": tarGzArchive(map[string]string{"bin/tool": "tool binary"}),
+					"docs.zip":      zipArchive(map[string]string{"docs/README.md": "readme"}),
+					"plain":         "plain asset",
+				})
+			})
+
+			It("extracts each archive into its own dir in the assets dir", func() {
+				assetsDir := filepath.Join(outputDir, "assets")
+				Ω(os.ReadFile(filepath.Join(assetsDir, "bundle", "bin", "tool"))).Should(Equal([]byte("tool binary")))
+				Ω(os.ReadFile(filepath.Join(assetsDir, "docs", "docs", "README.md"))).Should(Equal([]byte("readme")))
+				Ω(os.ReadFile(filepath.Join(assetsDir, "plain"))).Should(Equal([]byte("plain asset")))
+				Ω(filepath.Join(assetsDir, "bundle.tar.gz")).Should(BeARegularFile())
+			})
+		})
+
+		Context("and an archive would overwrite another asset", func() {
+			BeforeEach(func() {
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"bundle":        "verified asset",
+					"bundle.tar.gz": tarGzArchive(map[string]string{"tool": "tool binary"}),
+					"other.tar.gz":  tarGzArchive(map[string]string{"../bundle": "overwritten"}),
+				})
+				expectInFailure = true
+			})
+
+			It("fails the get without overwriting the asset", func() {
+				Ω(stderr.String()).Should(ContainSubstring("error unpacking release asset"))
+				Ω(os.ReadFile(filepath.Join(outputDir, "assets", "bundle"))).Should(Equal([]byte("verified asset")))
+			})
+		})
+
+		Context("and an archive escapes the assets dir", func() {
+			BeforeEach(func() {
+				createReleaseWithAssets(inputVersionTag, map[string]string{
+					"evil.tar.gz": tarGzArchive(map[string]string{"../evil": "evil"}),
+				})
+				expectInFailure = true
+			})
+
+			It("fails the get", func() {
+				Ω(stderr.String()).Should(ContainSubstring("outside of the destination directory"))
+				Ω(filepath.Join(outputDir, "evil")).ShouldNot(BeAnExistingFile())
+			})
+		})
 	})
 })
+
+// createReleaseWithAssets creates a release on the public test repo with the given tag that has the given assets,
+// keyed by name. The release is deleted when the spec finishes.
+func createReleaseWithAssets(tag string, assets map[string]string) {
+	rel, _, err := giteaClt.CreateRelease(Username, PublicRepo, gogitea.CreateReleaseOption{
+		TagName: tag,
+		Target:  "master",
+		Title:   tag,
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+	DeferCleanup(func() {
+		_, err := giteaClt.DeleteRelease(Username, PublicRepo, rel.ID)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	for name, contents := range assets {
+		_, _, err = giteaClt.CreateReleaseAttachment(Username, PublicRepo, rel.ID, strings.NewReader(contents), name)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+}
+
+// putSignedRelease creates a release on PublicRepo with the given assets using the put step, signing the assets and a
+// sha256 checksum manifest with the given private key.
+func putSignedRelease(tag, signingKey string, assets map[string]string) {
+	srcDir, err := os.MkdirTemp("", "concourse-gitea-release-resource-putsigned-*")
+	Ω(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(srcDir)
+
+	Ω(os.WriteFile(filepath.Join(srcDir, "name"), []byte(tag), 0o644)).Should(Succeed())
+	Ω(os.WriteFile(filepath.Join(srcDir, "tag"), []byte(tag), 0o644)).Should(Succeed())
+	Ω(os.WriteFile(filepath.Join(srcDir, "target"), []byte("master"), 0o644)).Should(Succeed())
+	Ω(os.Mkdir(filepath.Join(srcDir, "assets"), 0o755)).Should(Succeed())
+	for name, contents := range assets {
+		Ω(os.WriteFile(filepath.Join(srcDir, "assets", name), []byte(contents), 0o644)).Should(Succeed())
+	}
+
+	outRequest := resource.OutRequest{
+		Source: resource.Source{
+			GiteaURL:    serverURL,
+			Owner:       Username,
+			Repository:  PublicRepo,
+			AccessToken: accessToken,
+			SigningKey:  signingKey,
+		},
+		Params: resource.OutParams{
+			NamePath:          "name",
+			TagPath:           "tag",
+			TargetPath:        "target",
+			Globs:             []string{"assets/*"},
+			GenerateChecksums: "sha256",
+		},
+	}
+	jsonBytes, err := json.Marshal(outRequest)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	var stdout bytes.Buffer
+	cmd := resourceCommand("out", srcDir, srcDir)
+	cmd.Stdin = bytes.NewReader(jsonBytes)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	Ω(cmd.Run()).To(Succeed())
+
+	var output resource.InOutResponse
+	Ω(json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &output)).To(Succeed())
+	DeferCleanup(func() {
+		releaseID, err := strconv.ParseInt(output.Version.ID, 10, 64)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = giteaClt.DeleteRelease(Username, PublicRepo, releaseID)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+}
+
+// gpgSign returns the ASCII armored detached signature of the given contents.
+func gpgSign(signer *openpgp.Entity, contents string) string {
+	var sig bytes.Buffer
+	Ω(openpgp.ArmoredDetachSign(&sig, signer, strings.NewReader(contents), nil)).Should(Succeed())
+	return sig.String()
+}
+
+// expectSourceArchive checks that the given path is a source archive generated by Gitea, which has all the files in a
+// directory named after the repository.
+func expectSourceArchive(path, repo string) {
+	extractDir, err := os.MkdirTemp("", "concourse-gitea-release-resource-source-*")
+	Ω(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(extractDir)
+
+	Ω(archive.Extract(path, extractDir)).Should(Succeed())
+	Ω(filepath.Join(extractDir, repo)).Should(BeADirectory())
+}
+
+// tarGzArchive returns a gzipped tarball with the given files, keyed by path.
+func tarGzArchive(files map[string]string) string {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, contents := range files {
+		Ω(tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(contents))})).Should(Succeed())
+		_, err := tw.Write([]byte(contents))
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+	Ω(tw.Close()).Should(Succeed())
+	Ω(gz.Close()).Should(Succeed())
+	return buf.String()
+}
+
+// zipArchive returns a zip archive with the given files, keyed by path.
+func zipArchive(files map[string]string) string {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = w.Write([]byte(contents))
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+	Ω(zw.Close()).Should(Succeed())
+	return buf.String()
+}
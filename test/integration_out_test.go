@@ -2,14 +2,18 @@ package test
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	gohttp "net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	gogitea "code.gitea.io/sdk/gitea"
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/gruntwork-io/go-commons/random"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,9 +35,27 @@ var _ = Describe("Integration Out", func() {
 
 		srcDir       string
 		isPreRelease bool
+		isDraft      bool
+		publish      bool
+
+		assetConflict    string
+		pruneAssets      bool
+		parallelism      int
+		checksums        string
+		signingKey       string
+		generateNotes    bool
+		notesTemplate    string
+		template         bool
+		bodyStr          string
+		buildEnv         []string
+		bump             string
+		semverConstraint string
+		tagFilter        string
+		expectOutFailure bool
 
 		nameStr   string
 		tagStr    string
+		targetStr string
 		idStr     string
 		uniqueStr string
 		globs     []string
@@ -42,7 +64,7 @@ var _ = Describe("Integration Out", func() {
 	)
 
 	BeforeEach(func() {
-		rawClt, err := gitea.NewGiteaClient(ServerURL, accessToken)
+		rawClt, err := gitea.NewGiteaClient(serverURL, accessToken, nil)
 		Ω(err).ShouldNot(HaveOccurred())
 		clt = rawClt
 
@@ -56,30 +78,58 @@ var _ = Describe("Integration Out", func() {
 		uniqueStr = randomStr
 		nameStr = uniqueStr
 		tagStr = "a" + strings.ToLower(uniqueStr)
+		targetStr = "master"
 	})
 
 	JustBeforeEach(func() {
 		outRequest := resource.OutRequest{
 			Source: resource.Source{
-				GiteaURL:    ServerURL,
+				GiteaURL:    serverURL,
 				Owner:       Username,
 				Repository:  EmptyRepo,
 				AccessToken: accessToken,
 				PreRelease:  isPreRelease,
+				SigningKey:  signingKey,
+
+				SemverConstraint: semverConstraint,
+				TagFilter:        tagFilter,
 			},
 			Params: resource.OutParams{
 				NamePath:   "name",
 				TagPath:    "tag",
 				TargetPath: "target",
 				Globs:      globs,
+				Draft:      isDraft,
+				Publish:    publish,
+
+				AssetConflict: assetConflict,
+				PruneAssets:   pruneAssets,
+				Parallelism:   parallelism,
+
+				GenerateChecksums: checksums,
+				GenerateNotes:     generateNotes,
+				Template:          template,
+				Bump:              bump,
 			},
 		}
 
 		Ω(os.WriteFile(filepath.Join(srcDir, "name"), []byte(nameStr), 0o644)).Should(Succeed())
 		Ω(os.WriteFile(filepath.Join(srcDir, "tag"), []byte(tagStr), 0o644)).Should(Succeed())
-		Ω(os.WriteFile(filepath.Join(srcDir, "target"), []byte("master"), 0o644)).Should(Succeed())
-		Ω(os.WriteFile(filepath.Join(srcDir, "body"), []byte(defaultBodyStr), 0o644)).Should(Succeed())
-		outRequest.Params.BodyPath = "body"
+		Ω(os.WriteFile(filepath.Join(srcDir, "target"), []byte(targetStr), 0o644)).Should(Succeed())
+		if bodyStr == "" {
+			bodyStr = defaultBodyStr
+		}
+		if !generateNotes {
+			Ω(os.WriteFile(filepath.Join(srcDir, "body"), []byte(bodyStr), 0o644)).Should(Succeed())
+			outRequest.Params.BodyPath = "body"
+		}
+		if notesTemplate != "" {
+			Ω(os.WriteFile(filepath.Join(srcDir, "notes.tmpl"), []byte(notesTemplate), 0o644)).Should(Succeed())
+			outRequest.Params.NotesTemplatePath = "notes.tmpl"
+		}
+		if bump != "" {
+			outRequest.Params.TagPath = ""
+		}
 		if idStr != "" {
 			Ω(os.WriteFile(filepath.Join(srcDir, "id"), []byte(idStr), 0o644)).Should(Succeed())
 			outRequest.Params.IDPath = "id"
@@ -89,15 +139,14 @@ var _ = Describe("Integration Out", func() {
 		Ω(err).ShouldNot(HaveOccurred())
 
 		var stdout bytes.Buffer
-		cmd := exec.Command(
-			"docker", "run",
-			"-i", "--rm", "--network", "host",
-			"-v", fmt.Sprintf("%s:/input", srcDir),
-			imgTag, "/opt/resource/out", "/input",
-		)
+		cmd := resourceCommandWithEnv("out", srcDir, buildEnv, srcDir)
 		cmd.Stdin = bytes.NewReader(jsonBytes)
 		cmd.Stdout = &stdout
 		cmd.Stderr = os.Stderr
+		if expectOutFailure {
+			Ω(cmd.Run()).ShouldNot(Succeed())
+			return
+		}
 		Ω(cmd.Run()).To(Succeed())
 
 		var output resource.InOutResponse
@@ -116,8 +165,25 @@ var _ = Describe("Integration Out", func() {
 
 		srcDir = ""
 		isPreRelease = false
+		isDraft = false
+		publish = false
+		assetConflict = ""
+		pruneAssets = false
+		parallelism = 0
+		checksums = ""
+		signingKey = ""
+		generateNotes = false
+		notesTemplate = ""
+		template = false
+		bodyStr = ""
+		buildEnv = nil
+		bump = ""
+		semverConstraint = ""
+		tagFilter = ""
+		expectOutFailure = false
 		nameStr = ""
 		tagStr = ""
+		targetStr = ""
 		idStr = ""
 		globs = []string{}
 		clt = nil
@@ -157,6 +223,80 @@ var _ = Describe("Integration Out", func() {
 					Ω(newRelease.TagName).Should(Equal(tagStr))
 					Ω(len(newRelease.Attachments)).Should(Equal(2))
 				})
+
+				Context("and checksums", func() {
+					BeforeEach(func() {
+						checksums = "sha256"
+					})
+
+					It("creates release with assets and checksum manifest", func() {
+						Ω(len(newRelease.Attachments)).Should(Equal(3))
+						Ω(assetSizes(newRelease, "SHA256SUMS")).Should(HaveLen(1))
+
+						var manifest *gogitea.Attachment
+						for _, attc := range newRelease.Attachments {
+							if attc.Name == "SHA256SUMS" {
+								manifest = attc
+							}
+						}
+						manifestPath := filepath.Join(GinkgoT().TempDir(), "SHA256SUMS")
+						Ω(http.DownloadFileOverHTTP(gohttp.DefaultClient, manifest.DownloadURL, manifestPath, manifest.Size)).Should(Succeed())
+						Ω(os.ReadFile(manifestPath)).Should(Equal([]byte(
+							"e1fdf929f900720755a88879c2c98aa24612d5fda44a94511e6b850533f1d11f  myfile\n" +
+								"6a210a0742bf0c9f9599af5d38a63167e89025036478345f9271d5c22d0696fa  otherfile\n",
+						)))
+					})
+				})
+
+				Context("and signing key", func() {
+					var signer *openpgp.Entity
+
+					BeforeEach(func() {
+						key, err := NewTestGPGKey()
+						Ω(err).ShouldNot(HaveOccurred())
+						signer = key.Entity
+						signingKey = key.ArmoredPrivateKey
+						checksums = "sha256"
+					})
+
+					It("creates release with signed assets and checksum manifest", func() {
+						var names []string
+						for _, attc := range newRelease.Attachments {
+							names = append(names, attc.Name)
+						}
+						Ω(names).Should(ConsistOf(
+							"myfile", "myfile.asc", "otherfile", "otherfile.asc", "SHA256SUMS", "SHA256SUMS.asc",
+						))
+
+						for _, attc := range newRelease.Attachments {
+							if attc.Name != "myfile.asc" {
+								continue
+							}
+							sigPath := filepath.Join(GinkgoT().TempDir(), attc.Name)
+							Ω(http.DownloadFileOverHTTP(gohttp.DefaultClient, attc.DownloadURL, sigPath, attc.Size)).Should(Succeed())
+							sig, err := os.Open(sigPath)
+							Ω(err).ShouldNot(HaveOccurred())
+							defer sig.Close()
+
+							_, err = openpgp.CheckArmoredDetachedSignature(
+								openpgp.EntityList{signer}, strings.NewReader(asset1Str), sig, nil,
+							)
+							Ω(err).ShouldNot(HaveOccurred())
+						}
+					})
+				})
+
+				Context("and parallelism", func() {
+					BeforeEach(func() {
+						parallelism = 2
+					})
+
+					It("creates release with assets", func() {
+						Ω(assetSizes(newRelease, "myfile")).Should(ConsistOf(int64(len(asset1Str))))
+						Ω(assetSizes(newRelease, "otherfile")).Should(ConsistOf(int64(len(asset2Str))))
+						Ω(len(newRelease.Attachments)).Should(Equal(2))
+					})
+				})
 			})
 
 			Context("with glob omitting all", func() {
@@ -189,7 +329,7 @@ var _ = Describe("Integration Out", func() {
 					tmpFile.Close()
 					defer os.Remove(tmpFile.Name())
 
-					Ω(http.DownloadFileOverHTTP(attc.DownloadURL, tmpFile.Name())).Should(Succeed())
+					Ω(http.DownloadFileOverHTTP(gohttp.DefaultClient, attc.DownloadURL, tmpFile.Name(), attc.Size)).Should(Succeed())
 
 					Ω(os.ReadFile(tmpFile.Name())).Should(Equal([]byte(asset1Str)))
 				})
@@ -197,6 +337,268 @@ var _ = Describe("Integration Out", func() {
 		})
 	})
 
+	Context("when generating release notes", func() {
+		var baseTag, commitMsg string
+
+		BeforeEach(func() {
+			generateNotes = true
+
+			baseTag = "base-" + strings.ToLower(uniqueStr)
+			rel, _, err := giteaClt.CreateRelease(Username, EmptyRepo, gogitea.CreateReleaseOption{
+				TagName: baseTag,
+				Target:  "master",
+				Title:   baseTag,
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(func() {
+				_, err := giteaClt.DeleteRelease(Username, EmptyRepo, rel.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			commitMsg = "Change since " + baseTag
+			createCommit(EmptyRepo, commitMsg)
+		})
+
+		It("creates release with the commits since the previous release", func() {
+			Ω(newRelease.Note).Should(Equal("## Changes since " + baseTag + "\n\n- " + commitMsg + " (" +
+				releaseCommitSHA(newRelease)[:10] + ")\n"))
+		})
+
+		Context("with a notes template", func() {
+			BeforeEach(func() {
+				notesTemplate = "{{ .PreviousTag }}..{{ .Tag }}{{ range .Commits }}\n* {{ .Subject }}{{ end }}"
+			})
+
+			It("creates release with the rendered template", func() {
+				Ω(newRelease.Note).Should(Equal(baseTag + ".." + tagStr + "\n* " + commitMsg))
+			})
+		})
+	})
+
+	Context("when generating release notes for a release branch", func() {
+		var baseTag, branch, commitMsg string
+
+		BeforeEach(func() {
+			generateNotes = true
+
+			// The release branch is created before the previous release, so that the previous release tag is not an
+			// ancestor of the release branch.
+			branch = "release/" + strings.ToLower(uniqueStr)
+			createBranch(EmptyRepo, branch)
+			createCommit(EmptyRepo, "Change only on master")
+
+			baseTag = "base-" + strings.ToLower(uniqueStr)
+			rel, _, err := giteaClt.CreateRelease(Username, EmptyRepo, gogitea.CreateReleaseOption{
+				TagName: baseTag,
+				Target:  "master",
+				Title:   baseTag,
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(func() {
+				_, err := giteaClt.DeleteRelease(Username, EmptyRepo, rel.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			commitMsg = "Change on " + branch
+			createBranchCommit(EmptyRepo, branch, commitMsg)
+			targetStr = branch
+		})
+
+		It("creates release with only the commits on the release branch since the merge base", func() {
+			Ω(newRelease.Note).Should(Equal("## Changes since " + baseTag + "\n\n- " + commitMsg + " (" +
+				releaseCommitSHA(newRelease)[:10] + ")\n"))
+		})
+	})
+
+	Context("when templating the name and body", func() {
+		BeforeEach(func() {
+			template = true
+			buildEnv = []string{"BUILD_ID=42", "BUILD_PIPELINE_NAME=release", "ATC_EXTERNAL_URL=https://ci.example.com"}
+			nameStr = "{{ .Tag }} (build {{ .Build.ID }})"
+			bodyStr = "{{ .Build.PipelineName }} {{ .Build.ATCExternalURL }}{{ range .Assets }} {{ . }}{{ end }}"
+
+			Ω(os.Mkdir(filepath.Join(srcDir, "assets"), 0o755)).Should(Succeed())
+			Ω(os.WriteFile(filepath.Join(srcDir, "assets", "myfile"), []byte(asset1Str), 0o644)).Should(Succeed())
+			globs = []string{"assets/*"}
+		})
+
+		It("creates release with the rendered name and body", func() {
+			Ω(newRelease.Title).Should(Equal(tagStr + " (build 42)"))
+			Ω(newRelease.Note).Should(Equal("release https://ci.example.com myfile"))
+		})
+
+		Context("with an invalid template", func() {
+			BeforeEach(func() {
+				bodyStr = "{{ .Unknown }}"
+				expectOutFailure = true
+			})
+
+			It("fails without creating the release", func() {
+				_, _, err := clt.GetReleaseByTag(Username, EmptyRepo, tagStr)
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Context("when bumping the version", func() {
+		var major int
+
+		BeforeEach(func() {
+			// Use a unique major version, so that the releases from other specs don't affect the next version.
+			major = 100 + rand.Intn(1000000)
+			tagFilter = fmt.Sprintf(`^v%d\.`, major)
+
+			baseTag := fmt.Sprintf("v%d.1.0", major)
+			rel, _, err := giteaClt.CreateRelease(Username, EmptyRepo, gogitea.CreateReleaseOption{
+				TagName: baseTag,
+				Target:  "master",
+				Title:   baseTag,
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(func() {
+				_, err := giteaClt.DeleteRelease(Username, EmptyRepo, rel.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+
+		Context("by minor", func() {
+			BeforeEach(func() {
+				bump = "minor"
+			})
+
+			It("creates release with the next minor version", func() {
+				Ω(newRelease.TagName).Should(Equal(fmt.Sprintf("v%d.2.0", major)))
+				Ω(newRelease.IsPrerelease).Should(BeFalse())
+			})
+		})
+
+		Context("by prerelease", func() {
+			BeforeEach(func() {
+				bump = "prerelease"
+			})
+
+			It("creates prerelease with the next prerelease version", func() {
+				Ω(newRelease.TagName).Should(Equal(fmt.Sprintf("v%d.1.1-rc.1", major)))
+				Ω(newRelease.IsPrerelease).Should(BeTrue())
+			})
+		})
+
+		Context("and the semver constraint excludes the highest release", func() {
+			BeforeEach(func() {
+				bump = "patch"
+				semverConstraint = fmt.Sprintf("~> %d.1.0", major)
+
+				excludedTag := fmt.Sprintf("v%d.2.0", major)
+				rel, _, err := giteaClt.CreateRelease(Username, EmptyRepo, gogitea.CreateReleaseOption{
+					TagName: excludedTag,
+					Target:  "master",
+					Title:   excludedTag,
+					Note:    "excluded release",
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+				DeferCleanup(func() {
+					_, err := giteaClt.DeleteRelease(Username, EmptyRepo, rel.ID)
+					Ω(err).ShouldNot(HaveOccurred())
+				})
+			})
+
+			It("creates release with the next version within the constraint", func() {
+				Ω(newRelease.TagName).Should(Equal(fmt.Sprintf("v%d.1.1", major)))
+
+				excluded, err := gitea.GetReleaseByTag(clt, Username, EmptyRepo, fmt.Sprintf("v%d.2.0", major))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(excluded.Note).Should(Equal("excluded release"))
+			})
+		})
+
+		Context("and the next tag already exists", func() {
+			BeforeEach(func() {
+				bump = "minor"
+				expectOutFailure = true
+
+				// Deleting a release keeps its tag, leaving a tag without a release.
+				existingTag := fmt.Sprintf("v%d.2.0", major)
+				rel, _, err := giteaClt.CreateRelease(Username, EmptyRepo, gogitea.CreateReleaseOption{
+					TagName: existingTag,
+					Target:  "master",
+					Title:   existingTag,
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+				_, err = giteaClt.DeleteRelease(Username, EmptyRepo, rel.ID)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("fails without creating a release", func() {
+				_, err := gitea.GetReleaseByTag(clt, Username, EmptyRepo, fmt.Sprintf("v%d.2.0", major))
+				Ω(err).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Context("when creating a draft release", func() {
+		BeforeEach(func() {
+			isDraft = true
+			Ω(os.Mkdir(filepath.Join(srcDir, "assets"), 0o755)).Should(Succeed())
+			Ω(os.WriteFile(filepath.Join(srcDir, "assets", "myfile"), []byte(asset1Str), 0o644)).Should(Succeed())
+			globs = []string{"assets/*"}
+		})
+
+		It("creates draft release with assets", func() {
+			Ω(newRelease.Title).Should(Equal(uniqueStr))
+			Ω(newRelease.TagName).Should(Equal(tagStr))
+			Ω(newRelease.IsDraft).Should(BeTrue())
+			Ω(len(newRelease.Attachments)).Should(Equal(1))
+		})
+	})
+
+	Context("when publishing a new release", func() {
+		BeforeEach(func() {
+			publish = true
+			Ω(os.Mkdir(filepath.Join(srcDir, "assets"), 0o755)).Should(Succeed())
+			Ω(os.WriteFile(filepath.Join(srcDir, "assets", "myfile"), []byte(asset1Str), 0o644)).Should(Succeed())
+			globs = []string{"assets/*"}
+		})
+
+		It("creates published release with assets", func() {
+			Ω(newRelease.Title).Should(Equal(uniqueStr))
+			Ω(newRelease.TagName).Should(Equal(tagStr))
+			Ω(newRelease.IsDraft).Should(BeFalse())
+			Ω(len(newRelease.Attachments)).Should(Equal(1))
+		})
+	})
+
+	Context("when publishing an existing draft release", func() {
+		var existingID int64
+
+		BeforeEach(func() {
+			opts := gitea.CreateReleaseOpts{
+				Owner:   Username,
+				Repo:    EmptyRepo,
+				Tag:     tagStr,
+				Title:   "Draft release",
+				Target:  "master",
+				Body:    "Previously created draft release for tag",
+				IsDraft: true,
+			}
+			rel, err := gitea.CreateRelease(clt, opts)
+			Ω(err).ShouldNot(HaveOccurred())
+			existingID = rel.ID
+			idStr = strconv.FormatInt(existingID, 10)
+
+			publish = true
+			Ω(os.Mkdir(filepath.Join(srcDir, "assets"), 0o755)).Should(Succeed())
+			Ω(os.WriteFile(filepath.Join(srcDir, "assets", "myfile"), []byte(asset1Str), 0o644)).Should(Succeed())
+			globs = []string{"assets/*"}
+		})
+
+		It("publishes the draft release with assets", func() {
+			Ω(newRelease.ID).Should(Equal(existingID))
+			Ω(newRelease.Title).Should(Equal(uniqueStr))
+			Ω(newRelease.IsDraft).Should(BeFalse())
+			Ω(len(newRelease.Attachments)).Should(Equal(1))
+		})
+	})
+
 	Context("when updating an existing release", func() {
 		var existingID int64
 
@@ -275,7 +677,195 @@ var _ = Describe("Integration Out", func() {
 					Ω(newRelease.Note).Should(Equal(defaultBodyStr))
 					Ω(len(newRelease.Attachments)).Should(Equal(3))
 				})
+
+				Context("and prune assets is set", func() {
+					BeforeEach(func() {
+						pruneAssets = true
+					})
+
+					It("updates release with only new assets", func() {
+						Ω(newRelease.ID).Should(Equal(existingID))
+						Ω(assetSizes(newRelease, "myfile")).Should(ConsistOf(int64(len(asset1Str))))
+						Ω(assetSizes(newRelease, "otherfile")).Should(ConsistOf(int64(len(asset2Str))))
+						Ω(len(newRelease.Attachments)).Should(Equal(2))
+					})
+				})
+			})
+
+			Context("without new assets and prune assets is set", func() {
+				BeforeEach(func() {
+					pruneAssets = true
+				})
+
+				It("removes all assets from release", func() {
+					Ω(newRelease.ID).Should(Equal(existingID))
+					Ω(len(newRelease.Attachments)).Should(Equal(0))
+				})
+			})
+		})
+
+		Context("that has an existing asset with the same name as a new asset", func() {
+			const existingAssetStr = "hello world"
+
+			BeforeEach(func() {
+				tmpDir, err := os.MkdirTemp("", "")
+				Ω(err).ShouldNot(HaveOccurred())
+				DeferCleanup(os.RemoveAll, tmpDir)
+				existingPath := filepath.Join(tmpDir, "myfile")
+				Ω(os.WriteFile(existingPath, []byte(existingAssetStr), 0o644)).Should(Succeed())
+				Ω(gitea.UploadReleaseAssetFromPath(clt, existingPath, Username, EmptyRepo, existingID)).Should(Succeed())
+
+				Ω(os.Mkdir(filepath.Join(srcDir, "assets"), 0o755)).Should(Succeed())
+				Ω(os.WriteFile(filepath.Join(srcDir, "assets", "myfile"), []byte(asset1Str), 0o644)).Should(Succeed())
+				Ω(os.WriteFile(filepath.Join(srcDir, "assets", "otherfile"), []byte(asset2Str), 0o644)).Should(Succeed())
+				globs = []string{"assets/*"}
+			})
+
+			Context("and asset conflict is not set", func() {
+				It("keeps both assets", func() {
+					Ω(newRelease.ID).Should(Equal(existingID))
+					Ω(assetSizes(newRelease, "myfile")).Should(ConsistOf(int64(len(existingAssetStr)), int64(len(asset1Str))))
+					Ω(len(newRelease.Attachments)).Should(Equal(3))
+				})
+			})
+
+			Context("and prune assets is set", func() {
+				BeforeEach(func() {
+					pruneAssets = true
+				})
+
+				It("keeps only the new asset", func() {
+					Ω(newRelease.ID).Should(Equal(existingID))
+					Ω(assetSizes(newRelease, "myfile")).Should(ConsistOf(int64(len(asset1Str))))
+					Ω(len(newRelease.Attachments)).Should(Equal(2))
+				})
+
+				Context("and asset conflict is skip", func() {
+					BeforeEach(func() {
+						assetConflict = "skip"
+					})
+
+					It("keeps the existing asset", func() {
+						Ω(newRelease.ID).Should(Equal(existingID))
+						Ω(assetSizes(newRelease, "myfile")).Should(ConsistOf(int64(len(existingAssetStr))))
+						Ω(len(newRelease.Attachments)).Should(Equal(2))
+					})
+				})
+			})
+
+			Context("and asset conflict is replace", func() {
+				BeforeEach(func() {
+					assetConflict = "replace"
+				})
+
+				It("replaces the existing asset", func() {
+					Ω(newRelease.ID).Should(Equal(existingID))
+					Ω(assetSizes(newRelease, "myfile")).Should(ConsistOf(int64(len(asset1Str))))
+					Ω(len(newRelease.Attachments)).Should(Equal(2))
+				})
+			})
+
+			Context("and asset conflict is skip", func() {
+				BeforeEach(func() {
+					assetConflict = "skip"
+				})
+
+				It("keeps the existing asset", func() {
+					Ω(newRelease.ID).Should(Equal(existingID))
+					Ω(assetSizes(newRelease, "myfile")).Should(ConsistOf(int64(len(existingAssetStr))))
+					Ω(len(newRelease.Attachments)).Should(Equal(2))
+				})
+
+				Context("and checksums", func() {
+					BeforeEach(func() {
+						checksums = "sha256"
+					})
+
+					It("lists the checksum of the existing asset in the checksum manifest", func() {
+						Ω(assetSizes(newRelease, "myfile")).Should(ConsistOf(int64(len(existingAssetStr))))
+
+						var manifest *gogitea.Attachment
+						for _, attc := range newRelease.Attachments {
+							if attc.Name == "SHA256SUMS" {
+								manifest = attc
+							}
+						}
+						Ω(manifest).ShouldNot(BeNil())
+						manifestPath := filepath.Join(GinkgoT().TempDir(), "SHA256SUMS")
+						Ω(http.DownloadFileOverHTTP(gohttp.DefaultClient, manifest.DownloadURL, manifestPath, manifest.Size)).Should(Succeed())
+						Ω(os.ReadFile(manifestPath)).Should(Equal([]byte(
+							"b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9  myfile\n" +
+								"6a210a0742bf0c9f9599af5d38a63167e89025036478345f9271d5c22d0696fa  otherfile\n",
+						)))
+					})
+				})
+			})
+
+			Context("and asset conflict is fail", func() {
+				BeforeEach(func() {
+					assetConflict = "fail"
+					expectOutFailure = true
+				})
+
+				It("fails without uploading any assets", func() {
+					rel, _, err := clt.GetRelease(Username, EmptyRepo, existingID)
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(assetSizes(rel, "myfile")).Should(ConsistOf(int64(len(existingAssetStr))))
+					Ω(len(rel.Attachments)).Should(Equal(1))
+				})
 			})
 		})
 	})
 })
+
+// assetSizes returns the sizes of all the assets on the release with the given name.
+func assetSizes(release *gogitea.Release, name string) []int64 {
+	var sizes []int64
+	for _, attc := range release.Attachments {
+		if attc.Name == name {
+			sizes = append(sizes, attc.Size)
+		}
+	}
+	return sizes
+}
+
+// createCommit adds a new commit with the given message to the default branch of the repository.
+func createCommit(repo, message string) {
+	if fakeServer != nil {
+		_, err := fakeServer.CreateCommit(Username, repo, message)
+		Ω(err).ShouldNot(HaveOccurred())
+		return
+	}
+
+	fname, err := random.RandomString(6, random.Base62Chars)
+	Ω(err).ShouldNot(HaveOccurred())
+	_, _, err = giteaClt.CreateFile(Username, repo, fname, gogitea.CreateFileOptions{
+		FileOptions: gogitea.FileOptions{Message: message},
+		Content:     base64.StdEncoding.EncodeToString([]byte(message)),
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+}
+
+// createBranchCommit adds a new commit with the given message to the given branch of the repository.
+func createBranchCommit(repo, branch, message string) {
+	if fakeServer != nil {
+		_, err := fakeServer.CreateBranchCommit(Username, repo, branch, message)
+		Ω(err).ShouldNot(HaveOccurred())
+		return
+	}
+
+	fname, err := random.RandomString(6, random.Base62Chars)
+	Ω(err).ShouldNot(HaveOccurred())
+	_, _, err = giteaClt.CreateFile(Username, repo, fname, gogitea.CreateFileOptions{
+		FileOptions: gogitea.FileOptions{Message: message, BranchName: branch},
+		Content:     base64.StdEncoding.EncodeToString([]byte(message)),
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+}
+
+// releaseCommitSHA returns the SHA of the commit that the tag of the given release points to.
+func releaseCommitSHA(release *gogitea.Release) string {
+	tag, _, err := giteaClt.GetTag(Username, EmptyRepo, release.TagName)
+	Ω(err).ShouldNot(HaveOccurred())
+	return tag.Commit.SHA
+}
@@ -1,13 +1,21 @@
 package test
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
 	"testing"
 
 	"code.gitea.io/sdk/gitea"
+	"github.com/gruntwork-io/go-commons/shell"
 	"github.com/gruntwork-io/terratest/modules/docker"
 	"github.com/gruntwork-io/terratest/modules/git"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	"github.com/yorinasub17/concourse-gitea-release-resource/test/fakegitea"
 )
 
 const (
@@ -15,8 +23,14 @@ const (
 )
 
 var (
+	serverURL   string
 	accessToken string
 	giteaClt    *gitea.Client
+
+	// When running against the in-memory fake Gitea server, the resource commands are built locally into binDir
+	// instead of into a docker image.
+	fakeServer *fakegitea.Server
+	binDir     string
 )
 
 func TestIntegration(t *testing.T) {
@@ -30,10 +44,29 @@ var _ = BeforeSuite(func() {
 	root, err := git.GetRepoRootE(t)
 	Ω(err).ShouldNot(HaveOccurred())
 
-	buildOpts := &docker.BuildOptions{Tags: []string{imgTag}}
-	Ω(docker.BuildE(t, root, buildOpts)).To(Succeed())
+	if UseLiveServer() {
+		serverURL = ServerURL
+
+		buildOpts := &docker.BuildOptions{Tags: []string{imgTag}}
+		Ω(docker.BuildE(t, root, buildOpts)).To(Succeed())
+	} else {
+		srv, err := NewFakeServer()
+		Ω(err).ShouldNot(HaveOccurred())
+		fakeServer = srv
+		serverURL = srv.URL
+
+		tmpDir, err := os.MkdirTemp("", "concourse-gitea-release-resource-bin-*")
+		Ω(err).ShouldNot(HaveOccurred())
+		binDir = tmpDir
+
+		build := exec.Command("go", "build", "-o", binDir, "./cmd/check", "./cmd/in", "./cmd/out")
+		build.Dir = root
+		build.Stdout = os.Stderr
+		build.Stderr = os.Stderr
+		Ω(build.Run()).To(Succeed())
+	}
 
-	clt, err := gitea.NewClient(ServerURL, gitea.SetBasicAuth(Username, Password))
+	clt, err := gitea.NewClient(serverURL, gitea.SetBasicAuth(Username, Password))
 	Ω(err).ShouldNot(HaveOccurred())
 	giteaClt = clt
 
@@ -43,9 +76,61 @@ var _ = BeforeSuite(func() {
 })
 
 var _ = AfterSuite(func() {
-	t := GinkgoT()
-	Ω(docker.DeleteImageE(t, imgTag, nil)).To(Succeed())
-
 	_, err := giteaClt.DeleteAccessToken("IntegrationTestToken")
 	Ω(err).ShouldNot(HaveOccurred())
+
+	if UseLiveServer() {
+		t := GinkgoT()
+		Ω(docker.DeleteImageE(t, imgTag, nil)).To(Succeed())
+	} else {
+		fakeServer.Close()
+		Ω(os.RemoveAll(binDir)).To(Succeed())
+	}
 })
+
+// resourceCommand returns the command for running the given resource script (check, in, or out) with the provided
+// arguments. Against the live server, the script runs in the built docker image with the given directory mounted at
+// the same path, while against the fake server, the locally built binary is run directly.
+func resourceCommand(script, dir string, args ...string) *exec.Cmd {
+	return resourceCommandWithEnv(script, dir, nil, args...)
+}
+
+// resourceCommandWithEnv is the same as resourceCommand, except the given environment variables (in KEY=VALUE form)
+// are set for the resource script, like the build metadata that Concourse provides.
+func resourceCommandWithEnv(script, dir string, env []string, args ...string) *exec.Cmd {
+	if !UseLiveServer() {
+		cmd := exec.Command(filepath.Join(binDir, script), args...)
+		cmd.Env = append(os.Environ(), env...)
+		return cmd
+	}
+
+	dockerArgs := []string{"run", "-i", "--rm", "--network", "host"}
+	if dir != "" {
+		dockerArgs = append(dockerArgs, "-v", fmt.Sprintf("%s:%s", dir, dir))
+	}
+	for _, kv := range env {
+		dockerArgs = append(dockerArgs, "-e", kv)
+	}
+	dockerArgs = append(dockerArgs, imgTag, filepath.Join("/opt/resource", script))
+	return exec.Command("docker", append(dockerArgs, args...)...)
+}
+
+// fixOwnership chowns the files in the given directory to the current UID and GID so that it can be removed later.
+// This is only necessary when the resource ran in docker as root. We use a docker container so that we can run with
+// root without prompting for sudo password.
+func fixOwnership(dir string) {
+	if !UseLiveServer() {
+		return
+	}
+
+	u, err := user.Current()
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(
+		shell.RunShellCommand(
+			shell.NewShellOptions(),
+			"docker", "run",
+			"--rm", "-v", fmt.Sprintf("%s:/output", dir),
+			"alpine:3", "chown", "-R", fmt.Sprintf("%s:%s", u.Uid, u.Gid), "/output",
+		),
+	).ShouldNot(HaveOccurred())
+}
@@ -0,0 +1,33 @@
+package test
+
+import "fmt"
+
+// TestRelease describes a release that is cut on the test repositories.
+type TestRelease struct {
+	Tag        string
+	PreRelease bool
+}
+
+// SemverTestReleases returns the given number of test releases with semver tags, alternating between prerelease and
+// release (v0.0.0-alpha.1, v0.0.0, v0.0.1-alpha.1, v0.0.1, ...).
+func SemverTestReleases(count int) []TestRelease {
+	releases := []TestRelease{}
+	for i := 0; i < count; i++ {
+		release := TestRelease{Tag: fmt.Sprintf("v0.0.%d", i/2)}
+		if i%2 == 0 {
+			release.Tag += "-alpha.1"
+			release.PreRelease = true
+		}
+		releases = append(releases, release)
+	}
+	return releases
+}
+
+// NonSemverTestReleases are the releases cut on NonSemverRepo, in the order they are created. Note that
+// release-2024.04.3 is a backport that is cut after the newer releases.
+var NonSemverTestReleases = []TestRelease{
+	{Tag: "release-2024.05.1"},
+	{Tag: "build-100"},
+	{Tag: "release-2024.05.2"},
+	{Tag: "release-2024.04.3"},
+}
@@ -22,40 +22,46 @@ func main() {
 	clt := mustBasicAuthClient()
 
 	wg := new(sync.WaitGroup)
-	wg.Add(5)
+	wg.Add(6)
 
 	go func() {
 		defer wg.Done()
 		privateRepo := mustCreateRepo(clt, test.PrivateRepo, true)
-		mustSetupRepoWithTestReleases(clt, privateRepo, 4, false)
+		mustSetupRepoWithTestReleases(clt, privateRepo, test.SemverTestReleases(4), false)
 	}()
 
 	go func() {
 		defer wg.Done()
 		publicRepo := mustCreateRepo(clt, test.PublicRepo, false)
-		mustSetupRepoWithTestReleases(clt, publicRepo, 4, true)
+		mustSetupRepoWithTestReleases(clt, publicRepo, test.SemverTestReleases(4), true)
 	}()
 
 	go func() {
 		defer wg.Done()
 		publicRepoWithPrereleaseLatest := mustCreateRepo(clt, test.PublicRepoWithPrereleaseLatest, false)
-		mustSetupRepoWithTestReleases(clt, publicRepoWithPrereleaseLatest, 5, false)
+		mustSetupRepoWithTestReleases(clt, publicRepoWithPrereleaseLatest, test.SemverTestReleases(5), false)
 	}()
 
 	go func() {
 		defer wg.Done()
 		noreleasesRepo := mustCreateRepo(clt, test.NoReleasesRepo, false)
-		mustSetupRepoWithTestReleases(clt, noreleasesRepo, 0, false)
+		mustSetupRepoWithTestReleases(clt, noreleasesRepo, nil, false)
 	}()
 
 	go func() {
 		defer wg.Done()
 		emptyRepo := mustCreateRepo(clt, test.EmptyRepo, false)
-		mustSetupRepoWithTestReleases(clt, emptyRepo, 0, false)
+		mustSetupRepoWithTestReleases(clt, emptyRepo, nil, false)
+	}()
+
+	go func() {
+		defer wg.Done()
+		nonSemverRepo := mustCreateRepo(clt, test.NonSemverRepo, false)
+		mustSetupRepoWithTestReleases(clt, nonSemverRepo, test.NonSemverTestReleases, false)
 	}()
 
 	wg.Wait()
-	fmt.Fprintf(os.Stderr, "INFO: successfully created repos noreleases, empty, foo, foo-pre-latest, and fooprivate with test releases\n")
+	fmt.Fprintf(os.Stderr, "INFO: successfully created repos noreleases, empty, foo, foo-pre-latest, foo-nonsemver, and fooprivate with test releases\n")
 }
 
 func mustBasicAuthClient() *gitea.Client {
@@ -76,7 +82,7 @@ func mustCreateRepo(clt *gitea.Client, repoName string, private bool) *gitea.Rep
 	return repo
 }
 
-func mustSetupRepoWithTestReleases(clt *gitea.Client, repo *gitea.Repository, releaseCount int, includeAssets bool) {
+func mustSetupRepoWithTestReleases(clt *gitea.Client, repo *gitea.Repository, releases []test.TestRelease, includeAssets bool) {
 	cloneURL := repo.CloneURL
 	parsed, err := url.Parse(cloneURL)
 	if err != nil {
@@ -115,15 +121,9 @@ func mustSetupRepoWithTestReleases(clt *gitea.Client, repo *gitea.Repository, re
 		os.Exit(1)
 	}
 
-	// Add a file, and then update it N times, cutting a new release each time it is updated. Alternate between
-	// prerelease and new release.
-	for i := 0; i < releaseCount; i++ {
-		isPreRelease := false
-		releaseName := fmt.Sprintf("v0.0.%d", i/2)
-		if i%2 == 0 {
-			releaseName += "-alpha.1"
-			isPreRelease = true
-		}
+	// Add a file, and then update it for each release, cutting a new release each time it is updated.
+	for _, release := range releases {
+		releaseName := release.Tag
 
 		randomFPath := filepath.Join(tmpDir, "foo.txt")
 		uniqueStr, err := random.RandomString(6, random.Base62Chars)
@@ -149,7 +149,7 @@ func mustSetupRepoWithTestReleases(clt *gitea.Client, repo *gitea.Repository, re
 
 		// Sleep before cutting release to ensure repo is in sync on server
 		time.Sleep(1 * time.Second)
-		mustCutRelease(clt, repo.Owner.UserName, repo.Name, releaseName, sha, isPreRelease, includeAssets)
+		mustCutRelease(clt, repo.Owner.UserName, repo.Name, releaseName, sha, release.PreRelease, includeAssets)
 	}
 }
 